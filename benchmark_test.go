@@ -0,0 +1,75 @@
+package pegomock_test
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/petergtz/pegomock"
+)
+
+// BenchmarkMemoryAcrossTests simulates a large suite, in which every test creates a mock and records
+// some invocations with sizeable params. Since generated mocks own their GenericMock, the heap after
+// garbage collection must stay flat, no matter how many tests ran.
+func BenchmarkMemoryAcrossTests(b *testing.B) {
+	var heapBefore runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&heapBefore)
+
+	if retained := runTestsCreatingMocks(b.N); retained > 1 {
+		b.Fatalf("%v of %v mocks have been retained after their tests ended", retained, b.N)
+	}
+
+	b.StopTimer()
+	var heapAfter runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&heapAfter)
+	b.ReportMetric(float64(int64(heapAfter.HeapInuse)-int64(heapBefore.HeapInuse)), "heap-growth-bytes")
+	b.ReportMetric(float64(int64(heapAfter.HeapInuse)-int64(heapBefore.HeapInuse))/float64(b.N), "heap-growth-bytes/test")
+}
+
+func TestMocksAreNotRetainedAfterTheirTests(t *testing.T) {
+	const numTests = 100
+	if retained := runTestsCreatingMocks(numTests); retained > 1 {
+		t.Fatalf("%v of %v mocks have been retained after their tests ended", retained, numTests)
+	}
+}
+
+// runTestsCreatingMocks runs n simulated tests and returns how many of their mocks are still reachable afterwards.
+// The mocks are neither bound to a test nor cleaned up, so they can only be collected if nothing global, like the
+// map of GenericMocks of mocks that don't own one, references them.
+// Since a mock and its GenericMock reference each other, a finalizer cannot be set on the mock itself. Instead, it is
+// set on the receiver of the mock's default answer. The last mock may still be referenced by the ongoing state
+// of the goroutine, so up to one retained mock is expected.
+func runTestsCreatingMocks(n int) (retained int) {
+	payload := strings.Repeat("x", 1<<10)
+	failHandler := func(message string, callerSkip ...int) { panic(message) }
+	var collected int64
+	for i := 0; i < n; i++ {
+		defaultAnswer := &finalizedDefaultAnswer{}
+		runtime.SetFinalizer(defaultAnswer, func(*finalizedDefaultAnswer) { atomic.AddInt64(&collected, 1) })
+		display := NewMockDisplay(pegomock.WithFailHandler(failHandler), pegomock.WithDefaultAnswer(defaultAnswer.answer))
+		pegomock.When(display.MultipleParamsAndReturnValue(pegomock.AnyString(), pegomock.AnyInt())).ThenReturn("result")
+		for j := 0; j < 10; j++ {
+			display.MultipleParamsAndReturnValue(payload+string(rune(j)), j)
+		}
+		display.VerifyWasCalled(pegomock.Times(10)).MultipleParamsAndReturnValue(pegomock.AnyString(), pegomock.AnyInt())
+	}
+	// Finalizers run asynchronously after a garbage collection, so give them some time.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline) && atomic.LoadInt64(&collected) < int64(n-1); {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return n - int(atomic.LoadInt64(&collected))
+}
+
+// finalizedDefaultAnswer must neither be zero-sized nor small enough for the tiny allocator, which combines small
+// objects without pointers, since their finalizers may never run. Hence the pointer.
+type finalizedDefaultAnswer struct{ _ *int }
+
+func (*finalizedDefaultAnswer) answer(methodName string, params []pegomock.Param, returnTypes []reflect.Type) pegomock.ReturnValues {
+	return pegomock.ReturnZeroValues(methodName, params, returnTypes)
+}
@@ -0,0 +1,108 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// formatClosestInvocation describes the invocation of methodName that matches most of paramMatchers, and why its
+// params do not match. It returns "" if all invocations of methodName match.
+func (genericMock *GenericMock) formatClosestInvocation(methodName string, paramMatchers []Matcher) string {
+	closest := genericMock.closestInvocation(methodName, paramMatchers)
+	if closest == nil {
+		return ""
+	}
+	result := "Closest invocation was:\n\t" + methodName + "(" + formatParams(closest.params) + ")\n"
+	for i := 0; i < len(closest.params) || i < len(paramMatchers); i++ {
+		switch {
+		case i >= len(paramMatchers):
+			result += fmt.Sprintf("\t\tArgument %v: Expected: no argument; but got: %#v\n", i, closest.params[i])
+		case i >= len(closest.params):
+			result += fmt.Sprintf("\t\tArgument %v: Expected: %v; but got: no argument\n", i, paramMatchers[i])
+		case !paramMatchers[i].Matches(closest.params[i]):
+			result += fmt.Sprintf("\t\tArgument %v: %v\n", i, formatMismatch(paramMatchers[i], closest.params[i]))
+		}
+	}
+	return result
+}
+
+// tooFewInvocations reports whether more matching invocations could satisfy invocationCountMatcher.
+// Only then, the closest invocation that does not match is worth showing.
+func (genericMock *GenericMock) tooFewInvocations(invocationCountMatcher Matcher, methodName string, numMatchingInvocations int) bool {
+	for n := numMatchingInvocations + 1; n <= len(genericMock.invocationsOf(methodName)); n++ {
+		if invocationCountMatcher.Matches(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (genericMock *GenericMock) invocationsOf(methodName string) []MethodInvocation {
+	genericMock.Lock()
+	method, exists := genericMock.mockedMethods[methodName]
+	genericMock.Unlock()
+	if !exists {
+		return nil
+	}
+	method.Lock()
+	defer method.Unlock()
+	return append([]MethodInvocation{}, method.invocations...)
+}
+
+func (genericMock *GenericMock) closestInvocation(methodName string, paramMatchers []Matcher) *MethodInvocation {
+	invocations := genericMock.invocationsOf(methodName)
+	var closest *MethodInvocation
+	closestNumMatchingParams := -1
+	for i, invocation := range invocations {
+		if Matchers(paramMatchers).Matches(invocation.params) {
+			continue
+		}
+		numMatchingParams := 0
+		for j := 0; j < len(invocation.params) && j < len(paramMatchers); j++ {
+			if paramMatchers[j].Matches(invocation.params[j]) {
+				numMatchingParams++
+			}
+		}
+		if numMatchingParams > closestNumMatchingParams {
+			closest, closestNumMatchingParams = &invocations[i], numMatchingParams
+		}
+	}
+	return closest
+}
+
+// formatMismatch uses the FailureMessage of matcher, which must have just failed to match param, indenting
+// any further lines below the argument. For Eq matchers of composite values, a field-level diff is more readable.
+func formatMismatch(matcher Matcher, param Param) string {
+	if eqMatcher, isEqMatcher := matcher.(*EqMatcher); isEqMatcher && isComposite(eqMatcher.Value) {
+		if diff := eqMatcher.equality.diff(eqMatcher.Value, param); diff != "" {
+			return "Diff (-expected +actual):\n\t\t\t" + strings.Replace(strings.TrimRight(diff, "\n"), "\n", "\n\t\t\t", -1)
+		}
+	}
+	return strings.Replace(matcher.FailureMessage(), "\n", "\n\t", -1)
+}
+
+func isComposite(value Param) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Struct, reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// diff returns a human-readable diff of a and b, or "" if it cannot compute one.
+func (equality *equality) diff(a, b Param) (diff string) {
+	defer func() {
+		if recover() != nil {
+			diff = ""
+		}
+	}()
+	options := equality.options()
+	if len(options) == 0 {
+		options = cmp.Options{cmp.Exporter(func(reflect.Type) bool { return true })}
+	}
+	return cmp.Diff(a, b, options...)
+}
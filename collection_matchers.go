@@ -0,0 +1,145 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// CollectionMatcher matches slices, arrays or maps that satisfy the condition named by Name, e.g. SliceContaining,
+// on Values, which are the expected elements, length, keys or entries. It holds no funcs, so a later When
+// with an equal CollectionMatcher replaces the stubbing.
+type CollectionMatcher struct {
+	Name     string
+	Values   []Param
+	actual   Param
+	equality *equality
+	sync.Mutex
+}
+
+func (matcher *CollectionMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	collection := reflect.ValueOf(param)
+	switch matcher.Name {
+	case "SliceContaining":
+		return isSliceOrArray(collection) && matcher.sliceContaining(collection)
+	case "SliceContainingInAnyOrder":
+		return isSliceOrArray(collection) && matcher.sliceContainingInAnyOrder(collection)
+	case "SliceOfLen":
+		return isSliceOrArray(collection) && collection.Len() == matcher.Values[0].(int)
+	case "MapHasKey":
+		_, exists := mapValue(collection, matcher.Values[0])
+		return exists
+	case "MapHasEntry":
+		value, exists := mapValue(collection, matcher.Values[0])
+		return exists && matcher.equality.equal(value.Interface(), matcher.Values[1])
+	default:
+		panic(fmt.Sprintf("Unknown collection matcher %v", matcher.Name))
+	}
+}
+
+func (matcher *CollectionMatcher) sliceContaining(collection reflect.Value) bool {
+	for i := 0; i < collection.Len(); i++ {
+		if matcher.equality.equal(collection.Index(i).Interface(), matcher.Values[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (matcher *CollectionMatcher) sliceContainingInAnyOrder(collection reflect.Value) bool {
+	if collection.Len() != len(matcher.Values) {
+		return false
+	}
+	matched := make([]bool, len(matcher.Values))
+nextElem:
+	for i := 0; i < collection.Len(); i++ {
+		for j, elem := range matcher.Values {
+			if !matched[j] && matcher.equality.equal(collection.Index(i).Interface(), elem) {
+				matched[j] = true
+				continue nextElem
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (matcher *CollectionMatcher) useEquality(equality *equality) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matcher.equality == nil {
+		matcher.equality = equality
+	}
+}
+
+func (matcher *CollectionMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %#v", matcher, matcher.actual)
+}
+
+func (matcher *CollectionMatcher) String() string {
+	return fmt.Sprintf("%v(%v)", matcher.Name, formatParams(matcher.Values))
+}
+
+func registerCollectionMatcher(name string, values ...Param) {
+	RegisterMatcher(&CollectionMatcher{Name: name, Values: values})
+}
+
+// SliceContaining matches slices and arrays that contain elem.
+func SliceContaining(elem Param) []interface{} {
+	registerCollectionMatcher("SliceContaining", elem)
+	return nil
+}
+
+// SliceContainingInAnyOrder matches slices and arrays that contain exactly elems, in any order.
+func SliceContainingInAnyOrder(elems ...Param) []interface{} {
+	registerCollectionMatcher("SliceContainingInAnyOrder", elems...)
+	return nil
+}
+
+// SliceOfLen matches slices and arrays of length n.
+func SliceOfLen(n int) []interface{} {
+	registerCollectionMatcher("SliceOfLen", n)
+	return nil
+}
+
+// MapHasKey matches maps that contain key.
+func MapHasKey(key Param) map[interface{}]interface{} {
+	registerCollectionMatcher("MapHasKey", key)
+	return nil
+}
+
+// MapHasEntry matches maps that contain key with a value equal to value.
+func MapHasEntry(key Param, value Param) map[interface{}]interface{} {
+	registerCollectionMatcher("MapHasEntry", key, value)
+	return nil
+}
+
+func isSliceOrArray(collection reflect.Value) bool {
+	return collection.Kind() == reflect.Slice || collection.Kind() == reflect.Array
+}
+
+func mapValue(collection reflect.Value, key Param) (reflect.Value, bool) {
+	if collection.Kind() != reflect.Map {
+		return reflect.Value{}, false
+	}
+	keyType := collection.Type().Key()
+	keyValue := reflect.ValueOf(key)
+	if key == nil {
+		switch keyType.Kind() {
+		case reflect.Chan, reflect.Interface, reflect.Ptr:
+			keyValue = reflect.Zero(keyType)
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	if !keyValue.Type().AssignableTo(keyType) {
+		return reflect.Value{}, false
+	}
+	value := collection.MapIndex(keyValue)
+	return value, value.IsValid()
+}
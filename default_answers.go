@@ -0,0 +1,110 @@
+package pegomock
+
+import (
+	"reflect"
+	"sync"
+)
+
+// DefaultAnswer computes the return values of invocations that have not been stubbed.
+type DefaultAnswer func(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues
+
+// WithDefaultAnswer makes the mock use answer for all invocations that have not been stubbed.
+// Mocks returned by answer inherit the FailHandler of the mock unless they have their own.
+func WithDefaultAnswer(answer DefaultAnswer) Option {
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).defaultAnswer = answer })
+}
+
+// ReturnZeroValues returns the zero values of all return types. This is the default.
+func ReturnZeroValues(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
+	result := make(ReturnValues, len(returnTypes))
+	for i, returnType := range returnTypes {
+		result[i] = reflect.Zero(returnType).Interface()
+	}
+	return result
+}
+
+// ReturnEmptyValues returns non-nil empty values for slices, maps and channels, and zero values for all other return types.
+func ReturnEmptyValues(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
+	result := make(ReturnValues, len(returnTypes))
+	for i, returnType := range returnTypes {
+		result[i] = emptyValueOf(returnType)
+	}
+	return result
+}
+
+func emptyValueOf(t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Slice:
+		return reflect.MakeSlice(t, 0, 0).Interface()
+	case reflect.Map:
+		return reflect.MakeMap(t).Interface()
+	case reflect.Chan:
+		return reflect.MakeChan(reflect.ChanOf(reflect.BothDir, t.Elem()), 0).Convert(t).Interface()
+	default:
+		return reflect.Zero(t).Interface()
+	}
+}
+
+// ReturnMocks returns new mocks for interface return types, given there is a generated mock implementing the interface.
+// For all other return types, it behaves like ReturnEmptyValues. Returned mocks use ReturnMocks as default answer themselves.
+func ReturnMocks(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
+	result := ReturnEmptyValues(methodName, params, returnTypes)
+	for i, returnType := range returnTypes {
+		if factory := mockFactoryFor(returnType); factory != nil {
+			result[i] = factory(WithDefaultAnswer(ReturnMocks))
+		}
+	}
+	return result
+}
+
+// WithDeepStubs makes the mock return new mocks for interface return types of invocations that have not been stubbed,
+// given there is a generated mock implementing the interface. Repeated invocations with equal params return the same mocks,
+// which have deep stubs themselves. This allows to stub fluent APIs in one go:
+//
+//	When(client.Bucket("x").Object("y").Read()).ThenReturn(data, nil)
+func WithDeepStubs() Option {
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).deepStubs = true })
+}
+
+// MockFactory creates a new mock. Generated mocks register their constructor as MockFactory.
+type MockFactory func(options ...Option) Mock
+
+type registeredMockFactory struct {
+	mockType reflect.Type
+	factory  MockFactory
+}
+
+var (
+	mockFactoriesMutex sync.RWMutex
+	mockFactories      []registeredMockFactory
+)
+
+// RegisterMockFactory registers factory for mocks of type mockType, so that ReturnMocks can create them.
+// Generated mocks call it in their init function.
+func RegisterMockFactory(mockType reflect.Type, factory MockFactory) {
+	mockFactoriesMutex.Lock()
+	defer mockFactoriesMutex.Unlock()
+	mockFactories = append(mockFactories, registeredMockFactory{mockType, factory})
+}
+
+// mockFactoryFor returns the factory of the mock implementing interfaceType with the fewest methods,
+// which is most likely the mock generated for exactly that interface. It returns nil for error and empty interfaces.
+func mockFactoryFor(interfaceType reflect.Type) MockFactory {
+	if interfaceType.Kind() != reflect.Interface || interfaceType.NumMethod() == 0 || interfaceType == errorType {
+		return nil
+	}
+	mockFactoriesMutex.RLock()
+	defer mockFactoriesMutex.RUnlock()
+	var best *registeredMockFactory
+	for i, candidate := range mockFactories {
+		if candidate.mockType.Implements(interfaceType) && (best == nil || candidate.mockType.NumMethod() < best.mockType.NumMethod()) {
+			best = &mockFactories[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.factory
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
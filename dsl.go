@@ -16,8 +16,10 @@ package pegomock
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"sync"
 	"testing"
@@ -27,57 +29,279 @@ import (
 	"github.com/petergtz/pegomock/internal/verify"
 )
 
-var GlobalFailHandler FailHandler
+var (
+	GlobalFailHandler      FailHandler
+	globalFailHandlerMutex sync.RWMutex
+)
 
 func RegisterMockFailHandler(handler FailHandler) {
+	globalFailHandlerMutex.Lock()
+	defer globalFailHandlerMutex.Unlock()
 	GlobalFailHandler = handler
 }
-func RegisterMockTestingT(t *testing.T) {
-	RegisterMockFailHandler(BuildTestingTFailHandler(t))
-}
 
-var (
-	lastInvocation      *invocation
-	lastInvocationMutex sync.Mutex
-)
+func globalFailHandler() FailHandler {
+	globalFailHandlerMutex.RLock()
+	defer globalFailHandlerMutex.RUnlock()
+	return GlobalFailHandler
+}
 
-var globalArgMatchers Matchers
+// RegisterMockTestingT registers a global fail handler that reports failures via t.
+// When the test ends, the previous global fail handler is restored and
+// any ongoing stubbing or registered argument matchers are discarded.
+//
+// Because the fail handler is global, tests using RegisterMockTestingT cannot run in parallel.
+// Use NewContext or WithT instead.
+func RegisterMockTestingT(t testing.TB) {
+	t.Helper()
+	originalHandler := globalFailHandler()
+	RegisterMockFailHandler(BuildTestingTFailHandler(t))
+	t.Cleanup(func() {
+		discardOngoingState()
+		RegisterMockFailHandler(originalHandler)
+	})
+}
 
+// RegisterMatcher registers an argument matcher for the next invocation of a mock
+// within the current goroutine.
 func RegisterMatcher(matcher Matcher) {
-	globalArgMatchers.append(matcher)
+	withOngoingState(func(state *ongoingState) { state.argMatchers.append(matcher) })
 }
 
 type invocation struct {
-	genericMock *GenericMock
-	MethodName  string
-	Params      []Param
-	ReturnTypes []reflect.Type
+	genericMock              *GenericMock
+	MethodName               string
+	Params                   []Param
+	ReturnTypes              []reflect.Type
+	orderingInvocationNumber int
+	callThrough              func(delegate interface{}) ReturnValues
+
+	// stubbing and sequencedAnswer answered the invocation, and stubbingWasUsed is the stubbing's previous usage,
+	// so that usage and answer can be restored when When takes the invocation.
+	stubbing        *Stubbing
+	sequencedAnswer *sequencedAnswer
+	stubbingWasUsed bool
+
+	// deepStubs are the return values of an invocation answered by a deep stub. chainedFrom is the preceding invocation
+	// that returned the invoked mock as deep stub, so that When can remove all invocations of a chain.
+	deepStubs   ReturnValues
+	chainedFrom *invocation
+}
+
+func (invocation *invocation) callRealMethod() ReturnValues {
+	verify.Argument(invocation.genericMock != nil && invocation.genericMock.delegate != nil,
+		"Calling the real method requires a mock created with WithDelegate.")
+	verify.Argument(invocation.callThrough != nil,
+		"Calling the real method of %v requires a mock generated by a newer version of pegomock. Please regenerate it.", invocation.MethodName)
+	return invocation.callThrough(invocation.genericMock.delegate)
+}
+
+// answer computes the return values of a stubbed invocation.
+type answer func(invocation *invocation) ReturnValues
+
+func returnValuesAnswer(returnValues ReturnValues) answer {
+	return func(*invocation) ReturnValues { return returnValues }
 }
 
 type GenericMock struct {
 	sync.Mutex
 	mockedMethods map[string]*mockedMethod
-	fail          FailHandler
+	mock          Mock
+	strictness    Strictness
+	delegate      interface{}
+	defaultAnswer DefaultAnswer
+	deepStubs     bool
+	equality      equality
+
+	checkUnusedStubbings  bool
+	cleanupRegistered     bool
+	cleanupIgnored        bool
+	deferredVerifications []func()
 }
 
 func (genericMock *GenericMock) Invoke(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
-	lastInvocationMutex.Lock()
-	lastInvocation = &invocation{
+	return genericMock.InvokeWithCallThrough(methodName, params, returnTypes, nil)
+}
+
+// InvokeWithCallThrough is like Invoke, but additionally takes callThrough, which calls the
+// invoked method on a delegate. Generated mocks use it to support WithDelegate.
+func (genericMock *GenericMock) InvokeWithCallThrough(methodName string, params []Param, returnTypes []reflect.Type, callThrough func(delegate interface{}) ReturnValues) ReturnValues {
+	method := genericMock.getOrCreateMockedMethod(methodName)
+	invocation := &invocation{
 		genericMock: genericMock,
 		MethodName:  methodName,
 		Params:      params,
 		ReturnTypes: returnTypes,
+		callThrough: callThrough,
+	}
+	previousInvocation, argMatchersRegistered, stubbingFuncInProgress := setLastInvocation(invocation)
+	if previousInvocation.returnedDeepStub(genericMock) {
+		invocation.chainedFrom = previousInvocation
+	}
+	// Invocations that are part of a When must not run the answers of existing stubbings, since these might block
+	// or have side effects. This is only known for sure if argument matchers have been registered or When calls a func.
+	answer := method.recordInvocationAndFindAnswer(invocation, !argMatchersRegistered && !stubbingFuncInProgress)
+	if answer == nil {
+		return genericMock.invokeUnstubbed(method, invocation, argMatchersRegistered, stubbingFuncInProgress)
+	}
+	returnValues := answer(invocation)
+	if invocation.stubbing.deepStub {
+		invocation.deepStubs = returnValues
+	}
+	return returnValues
+}
+
+func (invocation *invocation) returnedDeepStub(genericMock *GenericMock) bool {
+	if invocation == nil {
+		return false
+	}
+	for _, returnValue := range invocation.deepStubs {
+		if returnValue == genericMock.mock {
+			return true
+		}
+	}
+	return false
+}
+
+// invokeUnstubbed handles invocations without matching stubbing. An invocation can be part of a When(...) or a verification,
+// which is likely the case when argument matchers have been registered or when When calls a func wrapping the invocation.
+func (genericMock *GenericMock) invokeUnstubbed(method *mockedMethod, invocation *invocation, argMatchersRegistered, stubbingFuncInProgress bool) ReturnValues {
+	stubbingInProgress := argMatchersRegistered || stubbingFuncInProgress
+	if genericMock.delegate != nil && !stubbingInProgress {
+		return invocation.callRealMethod()
+	}
+	// Deep stubs are also needed within When(func() { ... }), but with argument matchers, this must be the stubbed invocation itself.
+	if genericMock.deepStubs && !argMatchersRegistered {
+		if answer := genericMock.deepStubAnswer(method, invocation, stubbingInProgress); answer != nil {
+			invocation.deepStubs = answer(invocation)
+			return invocation.deepStubs
+		}
+	}
+	if stubbingInProgress {
+		return ReturnValues{}
+	}
+	if genericMock.strictness == Strict {
+		genericMock.failHandler()(fmt.Sprintf(
+			"Unstubbed invocation of %v(%v) on strict mock.\n\n\t%v",
+			method.name, formatParams(invocation.Params), formatStubbings(method.name, method.allStubbings())))
+	}
+	return genericMock.answerByDefault(invocation)
+}
+
+// deepStubAnswer stubs the invocation to return new mocks for all mockable interface return types. This way,
+// repeated invocations with equal params return the same mocks. It returns nil if no return type is mockable,
+// or if stubbingInProgress and the invocation matches a stubbing other than a deep stub.
+func (genericMock *GenericMock) deepStubAnswer(method *mockedMethod, invocation *invocation, stubbingInProgress bool) answer {
+	method.Lock()
+	defer method.Unlock()
+	// The invocation might have been deep stubbed before, e.g. when stubbing a chain again,
+	// or another goroutine might have stubbed it in the meantime.
+	if stubbing := method.stubbings.find(invocation.Params); stubbing != nil {
+		if stubbingInProgress && !stubbing.deepStub {
+			return nil
+		}
+		stubbing.used = true
+		return stubbing.nextAnswer().answer
+	}
+	var returnValues ReturnValues
+	for i, returnType := range invocation.ReturnTypes {
+		factory := mockFactoryFor(returnType)
+		if factory == nil {
+			continue
+		}
+		if returnValues == nil {
+			returnValues = ReturnEmptyValues(invocation.MethodName, invocation.Params, invocation.ReturnTypes)
+		}
+		options := []Option{WithDeepStubs()}
+		if fail := genericMock.mock.FailHandler(); fail != nil {
+			options = append(options, WithFailHandler(fail))
+		}
+		returnValues[i] = factory(options...)
+	}
+	if returnValues == nil {
+		return nil
+	}
+	paramMatchers := transformParamsIntoEqMatchers(invocation.Params)
+	genericMock.bindEquality(paramMatchers)
+	stubbing := &Stubbing{
+		paramMatchers:  paramMatchers,
+		answerSequence: []*sequencedAnswer{{answer: returnValuesAnswer(returnValues)}},
+		used:           true,
+		location:       "deep stub",
+		deepStub:       true,
+	}
+	method.stubbings = append(method.stubbings, stubbing)
+	return stubbing.nextAnswer().answer
+}
+
+func (genericMock *GenericMock) answerByDefault(invocation *invocation) ReturnValues {
+	if genericMock.defaultAnswer == nil {
+		return ReturnValues{}
+	}
+	returnValues := genericMock.defaultAnswer(invocation.MethodName, invocation.Params, invocation.ReturnTypes)
+	checkDefaultAnswerOf(invocation.MethodName, returnValues, invocation.ReturnTypes)
+	for _, returnValue := range returnValues {
+		if mock, isMock := returnValue.(Mock); isMock && mock.FailHandler() == nil {
+			mock.SetFailHandler(genericMock.mock.FailHandler())
+		}
 	}
-	lastInvocationMutex.Unlock()
-	return genericMock.getOrCreateMockedMethod(methodName).Invoke(params)
+	return returnValues
 }
 
-func (genericMock *GenericMock) stub(methodName string, paramMatchers []Matcher, returnValues ReturnValues) {
-	genericMock.stubWithCallback(methodName, paramMatchers, func([]Param) ReturnValues { return returnValues })
+// checkDefaultAnswerOf names the method in the failure of checkAssignabilityOf, because it happens
+// within the invocation of the mock, not within When.
+func checkDefaultAnswerOf(methodName string, returnValues ReturnValues, returnTypes []reflect.Type) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("Default answer for %v() returned invalid values: %v", methodName, r))
+		}
+	}()
+	checkAssignabilityOf(returnValues, returnTypes)
+}
+
+type cleanupRegistrar interface {
+	Cleanup(func())
 }
 
-func (genericMock *GenericMock) stubWithCallback(methodName string, paramMatchers []Matcher, callback func([]Param) ReturnValues) {
-	genericMock.getOrCreateMockedMethod(methodName).stub(paramMatchers, callback)
+func (genericMock *GenericMock) registerCleanup(t cleanupRegistrar) {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	if !runsCleanups(t) {
+		genericMock.cleanupIgnored = true
+		return
+	}
+	if genericMock.cleanupRegistered {
+		return
+	}
+	genericMock.cleanupRegistered = true
+	t.Cleanup(genericMock.cleanup)
+}
+
+// cleanup runs at the end of a test: first the deferred verifications, then the check for unused stubbings,
+// if enabled by WithUnusedStubbingsCheck. Finally, it drops all stubbings and invocations, so the mock does not keep
+// them alive after the test.
+func (genericMock *GenericMock) cleanup() {
+	genericMock.Lock()
+	deferredVerifications := genericMock.deferredVerifications
+	genericMock.Unlock()
+	for _, verification := range deferredVerifications {
+		verification()
+	}
+	if genericMock.checkUnusedStubbings {
+		VerifyAllStubbingsUsed(genericMock.mock)
+	}
+
+	genericMock.Lock()
+	genericMock.mockedMethods = make(map[string]*mockedMethod)
+	genericMock.deferredVerifications = nil
+	genericMock.cleanupRegistered = false
+	genericMock.Unlock()
+
+	genericMocksMutex.Lock()
+	delete(genericMocks, genericMock.mock)
+	genericMocksMutex.Unlock()
+
+	discardOngoingStatesOf(genericMock)
 }
 
 func (genericMock *GenericMock) getOrCreateMockedMethod(methodName string) *mockedMethod {
@@ -93,6 +317,25 @@ func (genericMock *GenericMock) reset(methodName string, paramMatchers []Matcher
 	genericMock.getOrCreateMockedMethod(methodName).reset(paramMatchers)
 }
 
+func (genericMock *GenericMock) resetAll() {
+	genericMock.Lock()
+	genericMock.mockedMethods = make(map[string]*mockedMethod)
+	genericMock.Unlock()
+	discardOngoingStatesOf(genericMock)
+}
+
+// clearInvocations keeps the invocation counter running, so that invocations after clearing
+// are still ordered correctly relative to earlier ones in an InOrderContext.
+func (genericMock *GenericMock) clearInvocations() {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	for _, method := range genericMock.mockedMethods {
+		method.Lock()
+		method.invocations = nil
+		method.Unlock()
+	}
+}
+
 func (genericMock *GenericMock) Verify(
 	inOrderContext *InOrderContext,
 	invocationCountMatcher Matcher,
@@ -104,23 +347,17 @@ func (genericMock *GenericMock) Verify(
 	if len(options) == 1 {
 		timeout = options[0].(time.Duration)
 	}
-	if genericMock.fail == nil && GlobalFailHandler == nil {
-		panic("No FailHandler set. Please use either RegisterMockFailHandler or RegisterMockTestingT or TODO to set a fail handler.")
-	}
-	fail := GlobalFailHandler
-	if genericMock.fail != nil {
-		fail = genericMock.fail
-	}
-	defer func() { globalArgMatchers = nil }() // We don't want a panic somewhere during verification screw our global argMatchers
-
-	if len(globalArgMatchers) != 0 {
-		verifyArgMatcherUse(globalArgMatchers, params)
+	fail := genericMock.failHandler()
+	argMatchers, hint := takeArgMatchers()
+	if len(argMatchers) != 0 {
+		verifyArgMatcherUse(argMatchers, params, hint)
+		genericMock.bindEquality(argMatchers)
 	}
 	startTime := time.Now()
 	// timeoutLoop:
 	for {
 		genericMock.Lock()
-		methodInvocations := genericMock.methodInvocations(methodName, params, globalArgMatchers)
+		methodInvocations := genericMock.methodInvocations(methodName, params, argMatchers)
 		genericMock.Unlock()
 		if inOrderContext != nil {
 			for _, methodInvocation := range methodInvocations {
@@ -143,21 +380,63 @@ func (genericMock *GenericMock) Verify(
 				continue
 			}
 			var paramsOrMatchers interface{} = formatParams(params)
-			if len(globalArgMatchers) != 0 {
-				paramsOrMatchers = formatMatchers(globalArgMatchers)
+			if len(argMatchers) != 0 {
+				paramsOrMatchers = formatMatchers(argMatchers)
 			}
 			timeoutInfo := ""
 			if timeout > 0 {
 				timeoutInfo = fmt.Sprintf(" after timeout of %v", timeout)
 			}
+			invocationCountFailureMessage := invocationCountMatcher.FailureMessage()
+			closestInvocation := ""
+			if genericMock.tooFewInvocations(invocationCountMatcher, methodName, len(methodInvocations)) {
+				paramMatchers := argMatchers
+				if len(paramMatchers) == 0 {
+					paramMatchers = transformParamsIntoEqMatchers(params)
+					genericMock.bindEquality(paramMatchers)
+				}
+				if closestInvocation = genericMock.formatClosestInvocation(methodName, paramMatchers); closestInvocation != "" {
+					closestInvocation = "\t" + closestInvocation + "\n"
+				}
+			}
 			fail(fmt.Sprintf(
-				"Mock invocation count for %v(%v) does not match expectation%v.\n\n\t%v\n\n\t%v",
-				methodName, paramsOrMatchers, timeoutInfo, invocationCountMatcher.FailureMessage(), formatInteractions(genericMock.allInteractions())))
+				"Mock invocation count for %v(%v) does not match expectation%v.\n\n\t%v\n\n%v\t%v",
+				methodName, paramsOrMatchers, timeoutInfo, invocationCountFailureMessage, closestInvocation, formatInteractions(genericMock.allInteractions())))
+			return methodInvocations
 		}
+		genericMock.markVerified(methodName, methodInvocations)
 		return methodInvocations
 	}
 }
 
+func (genericMock *GenericMock) failHandler() FailHandler {
+	if fail := genericMock.mock.FailHandler(); fail != nil {
+		return fail
+	}
+	if fail := globalFailHandler(); fail != nil {
+		return fail
+	}
+	panic("No FailHandler set. Please use either RegisterMockFailHandler or RegisterMockTestingT or TODO to set a fail handler.")
+}
+
+func (genericMock *GenericMock) markVerified(methodName string, methodInvocations []MethodInvocation) {
+	genericMock.Lock()
+	method, exists := genericMock.mockedMethods[methodName]
+	genericMock.Unlock()
+	if !exists {
+		return
+	}
+	method.Lock()
+	defer method.Unlock()
+	for i := range method.invocations {
+		for _, verifiedInvocation := range methodInvocations {
+			if method.invocations[i].orderingInvocationNumber == verifiedInvocation.orderingInvocationNumber {
+				method.invocations[i].verified = true
+			}
+		}
+	}
+}
+
 // TODO this doesn't need to be a method, can be a free function
 func (genericMock *GenericMock) GetInvocationParams(methodInvocations []MethodInvocation) [][]Param {
 	if len(methodInvocations) == 0 {
@@ -185,7 +464,7 @@ func (genericMock *GenericMock) methodInvocations(methodName string, params []Pa
 					invocations = append(invocations, invocation)
 				}
 			} else {
-				if reflect.DeepEqual(params, invocation.params) ||
+				if genericMock.paramsEqual(params, invocation.params) ||
 					(len(params) == 0 && len(invocation.params) == 0) {
 					invocations = append(invocations, invocation)
 				}
@@ -196,15 +475,30 @@ func (genericMock *GenericMock) methodInvocations(methodName string, params []Pa
 	return invocations
 }
 
+func (genericMock *GenericMock) paramsEqual(a, b []Param) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !genericMock.equality.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func formatInteractions(interactions map[string][]MethodInvocation) string {
 	if len(interactions) == 0 {
 		return "There were no other interactions with this mock"
 	}
-	result := "But other interactions with this mock were:\n"
+	return "But other interactions with this mock were:\n" + formatAllInvocations(interactions)
+}
+
+func formatAllInvocations(interactions map[string][]MethodInvocation) (result string) {
 	for _, methodName := range sortedMethodNames(interactions) {
 		result += formatInvocations(methodName, interactions[methodName])
 	}
-	return result
+	return
 }
 
 func formatInvocations(methodName string, invocations []MethodInvocation) (result string) {
@@ -224,6 +518,17 @@ func formatParams(params []Param) (result string) {
 	return
 }
 
+func formatStubbings(methodName string, stubbings Stubbings) string {
+	if len(stubbings) == 0 {
+		return "There are no stubbings for " + methodName
+	}
+	result := "Stubbings for " + methodName + " are:\n"
+	for _, stubbing := range stubbings {
+		result += "\t" + methodName + "(" + formatMatchers(stubbing.paramMatchers) + ")\n"
+	}
+	return result
+}
+
 func formatMatchers(matchers []Matcher) (result string) {
 	for i, matcher := range matchers {
 		if i > 0 {
@@ -255,6 +560,46 @@ func (genericMock *GenericMock) allInteractions() map[string][]MethodInvocation
 	return interactions
 }
 
+func (genericMock *GenericMock) unusedStubbings() map[string]Stubbings {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	unusedStubbings := make(map[string]Stubbings)
+	for methodName, method := range genericMock.mockedMethods {
+		for _, stubbing := range method.allStubbings() {
+			if !stubbing.used {
+				unusedStubbings[methodName] = append(unusedStubbings[methodName], stubbing)
+			}
+		}
+	}
+	return unusedStubbings
+}
+
+func formatUnusedStubbings(unusedStubbings map[string]Stubbings) (result string) {
+	methodNames := make([]string, 0, len(unusedStubbings))
+	for methodName := range unusedStubbings {
+		methodNames = append(methodNames, methodName)
+	}
+	sort.Strings(methodNames)
+	for _, methodName := range methodNames {
+		for _, stubbing := range unusedStubbings[methodName] {
+			result += fmt.Sprintf("\t%v(%v) stubbed at %v\n", methodName, formatMatchers(stubbing.paramMatchers), stubbing.location)
+		}
+	}
+	return
+}
+
+func (genericMock *GenericMock) unverifiedInteractions() map[string][]MethodInvocation {
+	interactions := make(map[string][]MethodInvocation)
+	for methodName, invocations := range genericMock.allInteractions() {
+		for _, invocation := range invocations {
+			if !invocation.verified {
+				interactions[methodName] = append(interactions[methodName], invocation)
+			}
+		}
+	}
+	return interactions
+}
+
 type mockedMethod struct {
 	sync.Mutex
 	name        string
@@ -262,31 +607,69 @@ type mockedMethod struct {
 	stubbings   Stubbings
 }
 
-func (method *mockedMethod) Invoke(params []Param) ReturnValues {
+// recordInvocationAndFindAnswer returns the answer of the matching stubbing, or nil if there is none.
+// The answer must be called without holding the method's lock, because it may invoke mocks itself.
+func (method *mockedMethod) recordInvocationAndFindAnswer(invocation *invocation, findAnswer bool) answer {
 	method.Lock()
-	method.invocations = append(method.invocations, MethodInvocation{params, globalInvocationCounter.nextNumber()})
-	method.Unlock()
-	stubbing := method.stubbings.find(params)
+	defer method.Unlock()
+	invocation.orderingInvocationNumber = globalInvocationCounter.nextNumber()
+	method.invocations = append(method.invocations, MethodInvocation{params: invocation.Params, orderingInvocationNumber: invocation.orderingInvocationNumber})
+	if !findAnswer {
+		return nil
+	}
+	stubbing := method.stubbings.find(invocation.Params)
 	if stubbing == nil {
-		return ReturnValues{}
+		return nil
 	}
-	return stubbing.Invoke(params)
+	invocation.stubbing, invocation.stubbingWasUsed = stubbing, stubbing.used
+	stubbing.used = true
+	invocation.sequencedAnswer = stubbing.nextAnswer()
+	return invocation.sequencedAnswer.answer
+}
+
+func (method *mockedMethod) allStubbings() Stubbings {
+	method.Lock()
+	defer method.Unlock()
+	return append(Stubbings{}, method.stubbings...)
 }
 
-func (method *mockedMethod) stub(paramMatchers Matchers, callback func([]Param) ReturnValues) {
+func (method *mockedMethod) stub(paramMatchers Matchers, location string, answer *sequencedAnswer) {
+	method.Lock()
+	defer method.Unlock()
 	stubbing := method.stubbings.findByMatchers(paramMatchers)
 	if stubbing == nil {
-		stubbing = &Stubbing{paramMatchers: paramMatchers}
+		stubbing = &Stubbing{paramMatchers: paramMatchers, location: location}
 		method.stubbings = append(method.stubbings, stubbing)
 	}
-	stubbing.callbackSequence = append(stubbing.callbackSequence, callback)
+	stubbing.answerSequence = append(stubbing.answerSequence, answer)
+}
+
+func (method *mockedMethod) updateAnswer(sequencedAnswer *sequencedAnswer, answer answer, times int) {
+	method.Lock()
+	defer method.Unlock()
+	sequencedAnswer.answer = answer
+	sequencedAnswer.times = times
 }
 
-func (method *mockedMethod) removeLastInvocation() {
-	method.invocations = method.invocations[:len(method.invocations)-1]
+// removeInvocation undoes the recording of invocation, because it has been made only for stubbing it with When.
+func (method *mockedMethod) removeInvocation(invocation *invocation) {
+	method.Lock()
+	defer method.Unlock()
+	if invocation.stubbing != nil {
+		invocation.stubbing.used = invocation.stubbingWasUsed
+		invocation.sequencedAnswer.uses--
+	}
+	for i, methodInvocation := range method.invocations {
+		if methodInvocation.orderingInvocationNumber == invocation.orderingInvocationNumber {
+			method.invocations = append(method.invocations[:i], method.invocations[i+1:]...)
+			return
+		}
+	}
 }
 
 func (method *mockedMethod) reset(paramMatchers Matchers) {
+	method.Lock()
+	defer method.Unlock()
 	method.stubbings.removeByMatchers(paramMatchers)
 }
 
@@ -309,13 +692,14 @@ var globalInvocationCounter = Counter{count: 1}
 type MethodInvocation struct {
 	params                   []Param
 	orderingInvocationNumber int
+	verified                 bool
 }
 
 type Stubbings []*Stubbing
 
 func (stubbings Stubbings) find(params []Param) *Stubbing {
 	for i := len(stubbings) - 1; i >= 0; i-- {
-		if stubbings[i].paramMatchers.Matches(params) {
+		if !stubbings[i].isExhausted() && stubbings[i].paramMatchers.Matches(params) {
 			return stubbings[i]
 		}
 	}
@@ -352,18 +736,46 @@ func matchersEqual(a, b Matchers) bool {
 }
 
 type Stubbing struct {
-	paramMatchers    Matchers
-	callbackSequence []func([]Param) ReturnValues
-	sequencePointer  int
+	paramMatchers   Matchers
+	answerSequence  []*sequencedAnswer
+	sequencePointer int
+	used            bool
+	location        string
+	deepStub        bool
+}
+
+// sequencedAnswer is an answer within the sequence of a stubbing. If times is 0, the answer is used once,
+// or indefinitely if it is the last one. Otherwise it is used exactly times times.
+type sequencedAnswer struct {
+	answer answer
+	times  int
+	uses   int
+}
+
+func (answer *sequencedAnswer) isUsedUp() bool {
+	if answer.times == 0 {
+		return answer.uses > 0
+	}
+	return answer.uses >= answer.times
 }
 
 func (stubbing *Stubbing) Invoke(params []Param) ReturnValues {
-	defer func() {
-		if stubbing.sequencePointer < len(stubbing.callbackSequence)-1 {
-			stubbing.sequencePointer++
-		}
-	}()
-	return stubbing.callbackSequence[stubbing.sequencePointer](params)
+	return stubbing.nextAnswer().answer(&invocation{Params: params})
+}
+
+func (stubbing *Stubbing) nextAnswer() *sequencedAnswer {
+	for stubbing.sequencePointer < len(stubbing.answerSequence)-1 && stubbing.answerSequence[stubbing.sequencePointer].isUsedUp() {
+		stubbing.sequencePointer++
+	}
+	answer := stubbing.answerSequence[stubbing.sequencePointer]
+	answer.uses++
+	return answer
+}
+
+// isExhausted reports whether the stubbing's last answer is limited and used up, so it no longer applies.
+func (stubbing *Stubbing) isExhausted() bool {
+	last := stubbing.answerSequence[len(stubbing.answerSequence)-1]
+	return last.times != 0 && last.uses >= last.times
 }
 
 type Matchers []Matcher
@@ -385,36 +797,98 @@ func (matchers *Matchers) append(matcher Matcher) {
 	*matchers = append(*matchers, matcher)
 }
 
-type ongoingStubbing struct {
+// OngoingStubbing defines the answers of a stubbed method, as started by When.
+type OngoingStubbing struct {
 	genericMock   *GenericMock
 	MethodName    string
 	ParamMatchers []Matcher
 	returnTypes   []reflect.Type
+	location      string
+
+	// prelude runs before the next answer. Until there is one, preludeAnswer runs it and returns zero values.
+	prelude       []func(*invocation)
+	preludeAnswer *sequencedAnswer
+}
+
+// addAnswer appends answer to the stubbing's answer sequence, preceded by any pending prelude.
+func (stubbing *OngoingStubbing) addAnswer(answer answer, times int) {
+	method := stubbing.genericMock.getOrCreateMockedMethod(stubbing.MethodName)
+	if stubbing.preludeAnswer != nil {
+		method.updateAnswer(stubbing.preludeAnswer, withPrelude(stubbing.prelude, answer), times)
+		stubbing.prelude, stubbing.preludeAnswer = nil, nil
+		return
+	}
+	method.stub(stubbing.ParamMatchers, stubbing.location, &sequencedAnswer{answer: answer, times: times})
+}
+
+func (stubbing *OngoingStubbing) addPrelude(prelude func(*invocation)) {
+	stubbing.prelude = append(stubbing.prelude, prelude)
+	answer := withPrelude(stubbing.prelude, returnValuesAnswer(ReturnValues{}))
+	method := stubbing.genericMock.getOrCreateMockedMethod(stubbing.MethodName)
+	if stubbing.preludeAnswer != nil {
+		method.updateAnswer(stubbing.preludeAnswer, answer, 0)
+		return
+	}
+	stubbing.preludeAnswer = &sequencedAnswer{answer: answer}
+	method.stub(stubbing.ParamMatchers, stubbing.location, stubbing.preludeAnswer)
 }
 
-func When(invocation ...interface{}) *ongoingStubbing {
+func withPrelude(prelude []func(*invocation), answer answer) answer {
+	return func(invocation *invocation) ReturnValues {
+		for _, f := range prelude {
+			f(invocation)
+		}
+		return answer(invocation)
+	}
+}
+
+func When(invocation ...interface{}) *OngoingStubbing {
 	callIfIsFunc(invocation)
+	lastInvocation := takeLastInvocation()
+	argMatchers, hint := takeArgMatchers()
 	verify.Argument(lastInvocation != nil,
 		"When() requires an argument which has to be 'a method call on a mock'.")
-	defer func() {
-		lastInvocationMutex.Lock()
-		lastInvocation = nil
-		lastInvocationMutex.Unlock()
-
-		globalArgMatchers = nil
-	}()
-	lastInvocation.genericMock.mockedMethods[lastInvocation.MethodName].removeLastInvocation()
+	for invocation := lastInvocation; invocation != nil; invocation = invocation.chainedFrom {
+		invocation.genericMock.getOrCreateMockedMethod(invocation.MethodName).removeInvocation(invocation)
+	}
 
-	paramMatchers := paramMatchersFromArgMatchersOrParams(globalArgMatchers, lastInvocation.Params)
+	paramMatchers := paramMatchersFromArgMatchersOrParams(argMatchers, hint, lastInvocation.Params)
+	lastInvocation.genericMock.bindEquality(paramMatchers)
 	lastInvocation.genericMock.reset(lastInvocation.MethodName, paramMatchers)
-	return &ongoingStubbing{
+	return &OngoingStubbing{
 		genericMock:   lastInvocation.genericMock,
 		MethodName:    lastInvocation.MethodName,
 		ParamMatchers: paramMatchers,
 		returnTypes:   lastInvocation.ReturnTypes,
+		location:      callerLocation(1),
 	}
 }
 
+// When starts stubbing methodName for params, or for the argument matchers registered instead of them.
+// Unlike the package-level When, it does not require invoking the mock. Generated typed stubbers use it.
+func (genericMock *GenericMock) When(methodName string, params []Param, returnTypes []reflect.Type) *OngoingStubbing {
+	argMatchers, hint := takeArgMatchers()
+	paramMatchers := paramMatchersFromArgMatchersOrParams(argMatchers, hint, params)
+	genericMock.bindEquality(paramMatchers)
+	genericMock.reset(methodName, paramMatchers)
+	return &OngoingStubbing{
+		genericMock:   genericMock,
+		MethodName:    methodName,
+		ParamMatchers: paramMatchers,
+		returnTypes:   returnTypes,
+		location:      callerLocation(2),
+	}
+}
+
+// callerLocation returns file:line of the caller skip levels above the function calling callerLocation.
+func callerLocation(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown location"
+	}
+	return fmt.Sprintf("%v:%v", file, line)
+}
+
 func callIfIsFunc(invocation []interface{}) {
 	if len(invocation) == 1 {
 		actualType := actualTypeOf(invocation[0])
@@ -423,6 +897,8 @@ func callIfIsFunc(invocation []interface{}) {
 				panic("When using 'When' with function that does not return a value, " +
 					"it expects a function with no arguments and no return value.")
 			}
+			withOngoingState(func(state *ongoingState) { state.stubbingFuncInProgress = true })
+			defer withOngoingState(func(state *ongoingState) { state.stubbingFuncInProgress = false })
 			reflect.ValueOf(invocation[0]).Call([]reflect.Value{})
 		}
 	}
@@ -434,15 +910,18 @@ func actualTypeOf(iface interface{}) reflect.Type {
 	return reflect.TypeOf(iface)
 }
 
-func paramMatchersFromArgMatchersOrParams(argMatchers []Matcher, params []Param) []Matcher {
+func paramMatchersFromArgMatchersOrParams(argMatchers []Matcher, hint string, params []Param) []Matcher {
 	if len(argMatchers) != 0 {
-		verifyArgMatcherUse(argMatchers, params)
+		verifyArgMatcherUse(argMatchers, params, hint)
 		return argMatchers
 	}
 	return transformParamsIntoEqMatchers(params)
 }
 
-func verifyArgMatcherUse(argMatchers []Matcher, params []Param) {
+func verifyArgMatcherUse(argMatchers []Matcher, params []Param, hint string) {
+	if len(argMatchers) > len(params) && hint != "" {
+		panic(fmt.Sprintf("Invalid use of matchers!\n\n %v matchers expected, %v recorded.\n\n%v", len(params), len(argMatchers), hint))
+	}
 	verify.Argument(len(argMatchers) == len(params),
 		"Invalid use of matchers!\n\n %v matchers expected, %v recorded.\n\n"+
 			"This error may occur if matchers are combined with raw values:\n"+
@@ -464,29 +943,61 @@ func transformParamsIntoEqMatchers(params []Param) []Matcher {
 	return paramMatchers
 }
 
+// NewGenericMock creates the GenericMock for mock. Generated mocks call it in their constructor
+// and keep the result, so that it gets garbage collected together with the mock.
+func NewGenericMock(mock Mock) *GenericMock {
+	return &GenericMock{
+		mockedMethods: make(map[string]*mockedMethod),
+		mock:          mock,
+	}
+}
+
+// genericMockOwner is implemented by generated mocks.
+type genericMockOwner interface {
+	GenericMock() *GenericMock
+}
+
+// genericMocks holds the GenericMocks of mocks that don't own one, e.g. hand-written mocks.
+// Entries are only removed when the mock is cleaned up at the end of a test.
 var (
 	genericMocksMutex sync.Mutex
 	genericMocks      = make(map[Mock]*GenericMock)
 )
 
 func GetGenericMockFrom(mock Mock) *GenericMock {
+	if owner, ok := mock.(genericMockOwner); ok {
+		if genericMock := owner.GenericMock(); genericMock != nil {
+			return genericMock
+		}
+	}
 	genericMocksMutex.Lock()
 	defer genericMocksMutex.Unlock()
 	if genericMocks[mock] == nil {
-		genericMocks[mock] = &GenericMock{
-			mockedMethods: make(map[string]*mockedMethod),
-			fail:          mock.FailHandler(),
-		}
+		genericMocks[mock] = NewGenericMock(mock)
 	}
 	return genericMocks[mock]
 }
 
-func (stubbing *ongoingStubbing) ThenReturn(values ...ReturnValue) *ongoingStubbing {
+func (stubbing *OngoingStubbing) ThenReturn(values ...ReturnValue) *OngoingStubbing {
+	checkAssignabilityOf(values, stubbing.returnTypes)
+	stubbing.addAnswer(returnValuesAnswer(values), 0)
+	return stubbing
+}
+
+// ThenReturnTimes makes the stubbed method return values for the next n invocations. Once a stubbing's
+// last answer is used up, the stubbing no longer applies. Instead, an earlier matching stubbing or the default answer does.
+func (stubbing *OngoingStubbing) ThenReturnTimes(n int, values ...ReturnValue) *OngoingStubbing {
+	verify.Argument(n > 0, "ThenReturnTimes() requires a positive number of times, but got %v.", n)
 	checkAssignabilityOf(values, stubbing.returnTypes)
-	stubbing.genericMock.stub(stubbing.MethodName, stubbing.ParamMatchers, values)
+	stubbing.addAnswer(returnValuesAnswer(values), n)
 	return stubbing
 }
 
+// ThenReturnOnce is ThenReturnTimes(1, values...).
+func (stubbing *OngoingStubbing) ThenReturnOnce(values ...ReturnValue) *OngoingStubbing {
+	return stubbing.ThenReturnTimes(1, values...)
+}
+
 func checkAssignabilityOf(stubbedReturnValues []ReturnValue, expectedReturnTypes []reflect.Type) {
 	verify.Argument(len(stubbedReturnValues) == len(expectedReturnTypes),
 		"Different number of return values")
@@ -506,22 +1017,140 @@ func checkAssignabilityOf(stubbedReturnValues []ReturnValue, expectedReturnTypes
 	}
 }
 
-func (stubbing *ongoingStubbing) ThenPanic(v interface{}) *ongoingStubbing {
-	stubbing.genericMock.stubWithCallback(
-		stubbing.MethodName,
-		stubbing.ParamMatchers,
-		func([]Param) ReturnValues { panic(v) })
+func (stubbing *OngoingStubbing) ThenPanic(v interface{}) *OngoingStubbing {
+	stubbing.addAnswer(func(*invocation) ReturnValues { panic(v) }, 0)
+	return stubbing
+}
+
+func (stubbing *OngoingStubbing) Then(callback func([]Param) ReturnValues) *OngoingStubbing {
+	stubbing.addAnswer(func(invocation *invocation) ReturnValues { return callback(invocation.Params) }, 0)
+	return stubbing
+}
+
+// ThenCallRealMethod makes the stubbed method call the mock's delegate. This requires a mock created with WithDelegate.
+func (stubbing *OngoingStubbing) ThenCallRealMethod() *OngoingStubbing {
+	verify.Argument(stubbing.genericMock.delegate != nil,
+		"ThenCallRealMethod() requires a mock created with WithDelegate.")
+	stubbing.addAnswer((*invocation).callRealMethod, 0)
+	return stubbing
+}
+
+// ThenDelay makes the stubbed method wait for d before running the next answer.
+// Without a next answer, it returns zero values.
+func (stubbing *OngoingStubbing) ThenDelay(d time.Duration) *OngoingStubbing {
+	stubbing.addPrelude(func(*invocation) { time.Sleep(d) })
+	return stubbing
+}
+
+// ThenBlockUntil makes the stubbed method wait until ch is closed or receives a value before running the next answer.
+// Without a next answer, it returns zero values.
+func (stubbing *OngoingStubbing) ThenBlockUntil(ch <-chan struct{}) *OngoingStubbing {
+	stubbing.addPrelude(func(*invocation) { <-ch })
+	return stubbing
+}
+
+// ThenSetArg makes the stubbed method set the value that the pointer param at index points to,
+// before running the next answer. Without a next answer, it returns zero values.
+// For variadic methods, index counts the variadic params individually.
+func (stubbing *OngoingStubbing) ThenSetArg(index int, value interface{}) *OngoingStubbing {
+	stubbing.addPrelude(func(invocation *invocation) { setPointee(invocation, index, value) })
+	return stubbing
+}
+
+func setPointee(invocation *invocation, index int, value interface{}) {
+	verify.Argument(index >= 0 && index < len(invocation.Params),
+		"ThenSetArg() got index %v, but %v(%v) has %v params.",
+		index, invocation.MethodName, formatParams(invocation.Params), len(invocation.Params))
+	pointer := reflect.ValueOf(invocation.Params[index])
+	verify.Argument(pointer.Kind() == reflect.Ptr && !pointer.IsNil(),
+		"ThenSetArg() requires param %v of %v(%v) to be a non-nil pointer.",
+		index, invocation.MethodName, formatParams(invocation.Params))
+	target := pointer.Elem()
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return
+	}
+	verify.Argument(reflect.TypeOf(value).AssignableTo(target.Type()),
+		"ThenSetArg() cannot assign value of type %T to param %v of type %v.", value, index, pointer.Type())
+	target.Set(reflect.ValueOf(value))
+}
+
+// ThenInvokeArg makes the stubbed method call the func param at index with args, before running the next answer.
+// Without a next answer, it returns zero values.
+func (stubbing *OngoingStubbing) ThenInvokeArg(index int, args ...interface{}) *OngoingStubbing {
+	stubbing.addPrelude(func(invocation *invocation) { argCall(invocation, "ThenInvokeArg", index, args)() })
 	return stubbing
 }
 
-func (stubbing *ongoingStubbing) Then(callback func([]Param) ReturnValues) *ongoingStubbing {
-	stubbing.genericMock.stubWithCallback(
-		stubbing.MethodName,
-		stubbing.ParamMatchers,
-		callback)
+// ThenInvokeArgAsync is like ThenInvokeArg, but calls the func param in a new goroutine.
+func (stubbing *OngoingStubbing) ThenInvokeArgAsync(index int, args ...interface{}) *OngoingStubbing {
+	stubbing.addPrelude(func(invocation *invocation) { go argCall(invocation, "ThenInvokeArgAsync", index, args)() })
+	return stubbing
+}
+
+// argCall validates the call of the func param at index with args and returns it without calling it yet.
+func argCall(invocation *invocation, answerName string, index int, args []interface{}) func() {
+	verify.Argument(index >= 0 && index < len(invocation.Params),
+		"%v() got index %v, but %v(%v) has %v params.",
+		answerName, index, invocation.MethodName, formatParams(invocation.Params), len(invocation.Params))
+	f := reflect.ValueOf(invocation.Params[index])
+	verify.Argument(f.Kind() == reflect.Func && !f.IsNil(),
+		"%v() requires param %v of %v(%v) to be a non-nil func.",
+		answerName, index, invocation.MethodName, formatParams(invocation.Params))
+	funcType := f.Type()
+	if funcType.IsVariadic() {
+		verify.Argument(len(args) >= funcType.NumIn()-1,
+			"%v() got %v args, but %v takes at least %v.", answerName, len(args), funcType, funcType.NumIn()-1)
+	} else {
+		verify.Argument(len(args) == funcType.NumIn(),
+			"%v() got %v args, but %v takes %v.", answerName, len(args), funcType, funcType.NumIn())
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var argType reflect.Type
+		if funcType.IsVariadic() && i >= funcType.NumIn()-1 {
+			argType = funcType.In(funcType.NumIn() - 1).Elem()
+		} else {
+			argType = funcType.In(i)
+		}
+		if arg == nil {
+			in[i] = reflect.Zero(argType)
+			continue
+		}
+		verify.Argument(reflect.TypeOf(arg).AssignableTo(argType),
+			"%v() cannot pass arg %v of type %T to %v.", answerName, i, arg, funcType)
+		in[i] = reflect.ValueOf(arg)
+	}
+	return func() { f.Call(in) }
+}
+
+// ThenBlockUntilContextDone makes the stubbed method wait until its context.Context param is done.
+// It then returns the context's Err() for error return values, and zero values otherwise.
+func (stubbing *OngoingStubbing) ThenBlockUntilContextDone() *OngoingStubbing {
+	stubbing.addAnswer(func(invocation *invocation) ReturnValues {
+		ctx := contextParamOf(invocation)
+		<-ctx.Done()
+		returnValues := ReturnZeroValues(invocation.MethodName, invocation.Params, invocation.ReturnTypes)
+		for i, returnType := range invocation.ReturnTypes {
+			if returnType == errorType {
+				returnValues[i] = ctx.Err()
+			}
+		}
+		return returnValues
+	}, 0)
 	return stubbing
 }
 
+func contextParamOf(invocation *invocation) context.Context {
+	for _, param := range invocation.Params {
+		if ctx, isContext := param.(context.Context); isContext {
+			return ctx
+		}
+	}
+	panic(fmt.Sprintf("ThenBlockUntilContextDone() requires a context.Context param, but %v(%v) has none.",
+		invocation.MethodName, formatParams(invocation.Params)))
+}
+
 type InOrderContext struct {
 	invocationCounter       int
 	lastInvokedMethodName   string
@@ -536,6 +1165,77 @@ type Matcher interface {
 	fmt.Stringer
 }
 
+// VerifyNoMoreInteractions fails if any of the given mocks has invocations
+// that have not been matched by a preceding verification.
+func VerifyNoMoreInteractions(mocks ...Mock) {
+	for _, mock := range mocks {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		unverifiedInteractions := genericMock.unverifiedInteractions()
+		genericMock.Unlock()
+		if len(unverifiedInteractions) != 0 {
+			genericMock.failHandler()(fmt.Sprintf(
+				"Expected no more interactions with this mock, but found unverified interactions:\n%v",
+				formatAllInvocations(unverifiedInteractions)))
+		}
+	}
+}
+
+// VerifyZeroInteractions fails if any of the given mocks has been invoked at all,
+// regardless of whether these invocations have been verified.
+func VerifyZeroInteractions(mocks ...Mock) {
+	for _, mock := range mocks {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		interactions := genericMock.allInteractions()
+		genericMock.Unlock()
+		if len(interactions) != 0 {
+			genericMock.failHandler()(fmt.Sprintf(
+				"Expected zero interactions with this mock, but found:\n%v",
+				formatAllInvocations(interactions)))
+		}
+	}
+}
+
+// VerifyAllStubbingsUsed fails if any of the given mocks has stubbings
+// that have never been used by an invocation.
+func VerifyAllStubbingsUsed(mocks ...Mock) {
+	for _, mock := range mocks {
+		genericMock := GetGenericMockFrom(mock)
+		if unusedStubbings := genericMock.unusedStubbings(); len(unusedStubbings) != 0 {
+			genericMock.failHandler()("Found unused stubbings:\n" + formatUnusedStubbings(unusedStubbings))
+		}
+	}
+}
+
+// Reset removes all stubbings and recorded invocations from the given mocks, so they can be reused,
+// e.g. in a Ginkgo BeforeEach.
+func Reset(mocks ...Mock) {
+	for _, mock := range mocks {
+		GetGenericMockFrom(mock).resetAll()
+	}
+}
+
+// ClearInvocations removes all recorded invocations from the given mocks, but keeps their stubbings.
+func ClearInvocations(mocks ...Mock) {
+	for _, mock := range mocks {
+		GetGenericMockFrom(mock).clearInvocations()
+	}
+}
+
+// DeferVerification registers a verification that runs when the test ends.
+// This requires the mock to be created with WithT.
+func DeferVerification(mock Mock, verification func()) {
+	genericMock := GetGenericMockFrom(mock)
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	verify.Argument(!genericMock.cleanupIgnored, cleanupIgnoredMessage,
+		"DeferVerification", "Please verify the mock at the end of the test instead.")
+	verify.Argument(genericMock.cleanupRegistered,
+		"DeferVerification requires a mock that is bound to a test. Please create the mock using WithT.")
+	genericMock.deferredVerifications = append(genericMock.deferredVerifications, verification)
+}
+
 func DumpInvocationsFor(mock Mock) {
 	fmt.Print(SDumpInvocationsFor(mock))
 }
@@ -561,7 +1261,7 @@ func SDumpInvocationsFor(mock Mock) string {
 // with a fail handler that simply annotates failures.  The original fail handler
 // is reset when InterceptMockFailures returns.
 func InterceptMockFailures(f func()) []string {
-	originalHandler := GlobalFailHandler
+	originalHandler := globalFailHandler()
 	failures := []string{}
 	RegisterMockFailHandler(func(message string, callerSkip ...int) {
 		failures = append(failures, message)
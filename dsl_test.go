@@ -15,10 +15,15 @@
 package pegomock_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -26,6 +31,7 @@ import (
 	. "github.com/petergtz/pegomock"
 	. "github.com/petergtz/pegomock/matchers"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/onsi/ginkgo"
 	"github.com/onsi/gomega"
 	"github.com/petergtz/pegomock"
@@ -33,22 +39,36 @@ import (
 )
 
 var (
-	BeforeEach       = ginkgo.BeforeEach
-	It               = ginkgo.It
-	FIt              = ginkgo.FIt
-	Describe         = ginkgo.Describe
-	Context          = ginkgo.Context
-	BeNil            = gomega.BeNil
-	BeTrue           = gomega.BeTrue
-	ConsistOf        = gomega.ConsistOf
-	ContainSubstring = gomega.ContainSubstring
-	MatchError       = gomega.MatchError
-	Equal            = gomega.Equal
-	Expect           = gomega.Expect
-	HaveLen          = gomega.HaveLen
-	HavePrefix       = gomega.HavePrefix
-	Panic            = gomega.Panic
-	SatisfyAll       = gomega.SatisfyAll
+	AfterEach            = ginkgo.AfterEach
+	BeforeEach           = ginkgo.BeforeEach
+	It                   = ginkgo.It
+	FIt                  = ginkgo.FIt
+	Describe             = ginkgo.Describe
+	Context              = ginkgo.Context
+	BeEmpty              = gomega.BeEmpty
+	BeNil                = gomega.BeNil
+	BeTrue               = gomega.BeTrue
+	BeFalse              = gomega.BeFalse
+	BeZero               = gomega.BeZero
+	BeIdenticalTo        = gomega.BeIdenticalTo
+	BeAssignableToTypeOf = gomega.BeAssignableToTypeOf
+	ConsistOf            = gomega.ConsistOf
+	ContainSubstring     = gomega.ContainSubstring
+	MatchError           = gomega.MatchError
+	Equal                = gomega.Equal
+	Eventually           = gomega.Eventually
+	Expect               = gomega.Expect
+	Consistently         = gomega.Consistently
+	Receive              = gomega.Receive
+	BeNumerically        = gomega.BeNumerically
+	HaveLen              = gomega.HaveLen
+	Succeed              = gomega.Succeed
+	HaveOccurred         = gomega.HaveOccurred
+	HavePrefix           = gomega.HavePrefix
+	HaveSuffix           = gomega.HaveSuffix
+	MatchRegexp          = gomega.MatchRegexp
+	Panic                = gomega.Panic
+	SatisfyAll           = gomega.SatisfyAll
 )
 
 var checkThatInterfaceIsImplemented test_interface.Display = NewMockDisplay()
@@ -623,6 +643,10 @@ var _ = Describe("MockDisplay", func() {
 			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWith(
 				"Mock invocation count for Flash(\"wrong string\", -987) " +
 					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
+					"\tClosest invocation was:\n" +
+					"\tFlash(\"Hello\", 123)\n" +
+					"\t\tArgument 0: Expected: wrong string; but got: Hello\n" +
+					"\t\tArgument 1: Expected: -987; but got: 123\n\n" +
 					"\tBut other interactions with this mock were:\n" +
 					"\tFlash(\"Hello\", 123)\n" +
 					"\tFlash(\"Again\", 456)\n",
@@ -636,6 +660,10 @@ var _ = Describe("MockDisplay", func() {
 			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWith(
 				"Mock invocation count for Flash(\"wrong string\", -987) " +
 					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
+					"\tClosest invocation was:\n" +
+					"\tFlash(\"Hello\", 123)\n" +
+					"\t\tArgument 0: Expected: wrong string; but got: Hello\n" +
+					"\t\tArgument 1: Expected: -987; but got: 123\n\n" +
 					"\tBut other interactions with this mock were:\n" +
 					"\tFlash(\"Hello\", 123)\n" +
 					"\tShow(\"Again\")\n"),
@@ -644,15 +672,60 @@ var _ = Describe("MockDisplay", func() {
 
 		It("formats params in interactions with Go syntax for better readability", func() {
 			display.NetHttpRequestParam(http.Request{Host: "x.com"})
-			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "y.com"}) }).To(PanicWith(
+			// The closest invocation in between shows a diff, whose whitespace go-cmp randomizes on purpose.
+			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "y.com"}) }).To(PanicWithMessageTo(SatisfyAll(HavePrefix(
 				`Mock invocation count for NetHttpRequestParam(http.Request{Method:"", URL:(*url.URL)(nil), Proto:"", ProtoMajor:0, ProtoMinor:0, Header:http.Header(nil), Body:io.ReadCloser(nil), GetBody:(func() (io.ReadCloser, error))(nil), ContentLength:0, TransferEncoding:[]string(nil), Close:false, Host:"y.com", Form:url.Values(nil), PostForm:url.Values(nil), MultipartForm:(*multipart.Form)(nil), Trailer:http.Header(nil), RemoteAddr:"", RequestURI:"", TLS:(*tls.ConnectionState)(nil), Cancel:(<-chan struct {})(nil), Response:(*http.Response)(nil), ctx:context.Context(nil)}) does not match expectation.
 
 	Expected: 1; but got: 0
 
+	Closest invocation was:`), HaveSuffix(`
 	But other interactions with this mock were:
 	NetHttpRequestParam(http.Request{Method:"", URL:(*url.URL)(nil), Proto:"", ProtoMajor:0, ProtoMinor:0, Header:http.Header(nil), Body:io.ReadCloser(nil), GetBody:(func() (io.ReadCloser, error))(nil), ContentLength:0, TransferEncoding:[]string(nil), Close:false, Host:"x.com", Form:url.Values(nil), PostForm:url.Values(nil), MultipartForm:(*multipart.Form)(nil), Trailer:http.Header(nil), RemoteAddr:"", RequestURI:"", TLS:(*tls.ConnectionState)(nil), Cancel:(<-chan struct {})(nil), Response:(*http.Response)(nil), ctx:context.Context(nil)})
 `,
-			))
+			))))
+		})
+
+		It("shows the closest invocation, which matches the most arguments", func() {
+			display.Flash("Hello", 123)
+			display.Flash("wrong string", 456)
+
+			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWithMessageTo(ContainSubstring(
+				"\tClosest invocation was:\n" +
+					"\tFlash(\"wrong string\", 456)\n" +
+					"\t\tArgument 1: Expected: -987; but got: 456\n\n",
+			)))
+		})
+
+		It("shows the failure messages of argument matchers for the closest invocation", func() {
+			display.NetHttpRequestPtrParam(&http.Request{Host: "x.com", Method: "GET"})
+
+			Expect(func() {
+				display.VerifyWasCalledOnce().NetHttpRequestPtrParam(PtrToHttpRequestWithFields(map[string]interface{}{"Host": "y.com", "Method": "GET"}))
+			}).To(PanicWithMessageTo(ContainSubstring(
+				"\t\tArgument 0: Mismatching fields:\n" +
+					"\t\t\tHost: Expected: \"y.com\"; but got: \"x.com\"\n\n",
+			)))
+		})
+
+		It("shows a field-level diff for composite values", func() {
+			display.NetHttpRequestParam(http.Request{Host: "x.com"})
+
+			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "y.com"}) }).To(PanicWithMessageTo(SatisfyAll(
+				ContainSubstring("\t\tArgument 0: Diff (-expected +actual):\n"),
+				// cmp.Diff randomly uses non-breaking spaces, which \s does not match.
+				MatchRegexp(`\t\t\t-[\s\x{00a0}]+Host:[\s\x{00a0}]+"y\.com"`),
+				MatchRegexp(`\t\t\t\+[\s\x{00a0}]+Host:[\s\x{00a0}]+"x\.com"`),
+			)))
+		})
+
+		It("does not show a closest invocation if there were too many invocations", func() {
+			display.Flash("Hello", 123)
+			display.Flash("Again", 456)
+
+			Expect(func() { display.VerifyWasCalled(Never()).Flash("Hello", 123) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Flash(\"Hello\", 123) does not match expectation.\n\n\tExpected: 0; but got: 1\n\n" +
+					"\tBut other interactions with this mock were:\n",
+			)))
 		})
 
 		It("shows no interactions if there were none", func() {
@@ -886,59 +959,1605 @@ var _ = Describe("MockDisplay", func() {
 		})
 	})
 
-	Context("channels", func() {
+	Describe("Limited-count stubbings", func() {
+		It("falls back to the default answer once the stubbing is used up", func() {
+			When(display.SomeValue()).ThenReturnTimes(2, "limited")
 
-		Context("using send-/receive-only channels in return types", func() {
-			It("allows to return non-direction channels from callbacks", func() {
-				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
-					return []ReturnValue{make(chan string), make(chan error)}
-				})
-				display.ChanReturnValues()
-			})
+			Expect(display.SomeValue()).To(Equal("limited"))
+			Expect(display.SomeValue()).To(Equal("limited"))
+			Expect(display.SomeValue()).To(Equal(""))
+		})
 
-			It("allows to return directed channels from callbacks", func() {
-				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
-					return []ReturnValue{make(<-chan string), make(chan<- error)}
-				})
-				display.ChanReturnValues()
-			})
+		It("falls back to an earlier, broader stubbing", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("broad")
+			When(display.MultipleParamsAndReturnValue("Hello", 1)).ThenReturnOnce("once")
 
-			It("does not allow to return directed channels from callbacks with wrong direction", func() {
-				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
-					return []ReturnValue{make(chan<- string), make(chan<- error)}
-				})
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("once"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("broad"))
+		})
 
-				Expect(func() { display.ChanReturnValues() }).To(PanicWithMessageTo(MatchError(
-					"interface conversion: pegomock.ReturnValue is chan<- string, not <-chan string",
-				)))
+		It("can be followed by an unlimited answer", func() {
+			When(display.ErrorReturnValue()).ThenReturnTimes(2, errors.New("fail")).ThenReturn(nil)
 
-			})
+			Expect(display.ErrorReturnValue()).To(HaveOccurred())
+			Expect(display.ErrorReturnValue()).To(HaveOccurred())
+			Expect(display.ErrorReturnValue()).NotTo(HaveOccurred())
+			Expect(display.ErrorReturnValue()).NotTo(HaveOccurred())
 		})
 
-		Context("using send-/receive-only channels", func() {
-			It("generates the mock method with correct channel directions", func() {
-				var stringReadChan <-chan string
-				var errorWriteChan chan<- error
-				display.ChanParams(stringReadChan, errorWriteChan)
-			})
+		It("uses consecutive limited answers in order", func() {
+			display.Stub().SomeValue().ThenReturnOnce("first").ThenReturnTimes(2, "second")
+
+			Expect(display.SomeValue()).To(Equal("first"))
+			Expect(display.SomeValue()).To(Equal("second"))
+			Expect(display.SomeValue()).To(Equal("second"))
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("is not used up by the invocation within a later When", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnOnce("once")
+			When(display.MultipleParamsAndReturnValue("Hello", 1)).ThenReturn("Hello")
+
+			Expect(display.MultipleParamsAndReturnValue("Bye", 2)).To(Equal("once"))
+			Expect(display.MultipleParamsAndReturnValue("Bye", 2)).To(Equal(""))
+		})
+
+		It("keeps all of its limited answers when stubbing again with different params", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnTimes(2, "twice").ThenReturn("then")
+			When(display.MultipleParamsAndReturnValue("Hello", 1)).ThenReturn("Hello")
+			When(display.MultipleParamsAndReturnValue("Hello", 2)).ThenReturn("Hello")
+
+			Expect(display.MultipleParamsAndReturnValue("Bye", 3)).To(Equal("twice"))
+			Expect(display.MultipleParamsAndReturnValue("Bye", 3)).To(Equal("twice"))
+			Expect(display.MultipleParamsAndReturnValue("Bye", 3)).To(Equal("then"))
+		})
+
+		It("does not allow non-positive counts", func() {
+			Expect(func() { When(display.SomeValue()).ThenReturnTimes(0, "value") }).To(PanicWith(
+				"ThenReturnTimes() requires a positive number of times, but got 0.",
+			))
 		})
 	})
-})
 
-func flattenStringSliceOfSlices(sliceOfSlices [][]string) (result []string) {
-	for _, slice := range sliceOfSlices {
-		result = append(result, slice...)
-	}
-	return
-}
+	Describe("Delaying and blocking answers", func() {
+		It("delays the next answer with ThenDelay", func() {
+			When(display.SomeValue()).ThenDelay(50 * time.Millisecond).ThenReturn("delayed")
 
-type expectation struct {
-	method   string
-	expected string
-	actual   string
-}
+			start := time.Now()
+			Expect(display.SomeValue()).To(Equal("delayed"))
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
 
-func (e expectation) string() string {
-	return fmt.Sprintf("Mock invocation count for %v does not match expectation.\n\n\tExpected: %v; but got: %v",
-		e.method, e.expected, e.actual)
+		It("returns zero values after ThenDelay without a next answer", func() {
+			When(display.SomeValue()).ThenReturn("first").ThenDelay(time.Millisecond)
+
+			Expect(display.SomeValue()).To(Equal("first"))
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("blocks until the channel is closed with ThenBlockUntil", func() {
+			unblock := make(chan struct{})
+			When(display.SomeValue()).ThenReturn("first").ThenBlockUntil(unblock).ThenReturn("second")
+			Expect(display.SomeValue()).To(Equal("first"))
+
+			result := make(chan string)
+			go func() { result <- display.SomeValue() }()
+
+			Consistently(result, 50*time.Millisecond).ShouldNot(Receive())
+			close(unblock)
+			Eventually(result).Should(Receive(Equal("second")))
+		})
+
+		It("blocks until the context is done and returns its error with ThenBlockUntilContextDone", func() {
+			object := NewMockObject()
+			ctx, cancel := context.WithCancel(context.Background())
+			object.Stub().ReadWithContext(ctx).ThenBlockUntilContextDone()
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			data, e := object.ReadWithContext(ctx)
+
+			Expect(data).To(BeNil())
+			Expect(e).To(Equal(context.Canceled))
+		})
+
+		It("does not delay stubbing again", func() {
+			When(display.SomeValue()).ThenDelay(time.Hour)
+
+			done := make(chan bool, 1)
+			go func() {
+				When(func() { display.SomeValue() }).ThenReturn("stubbed again")
+				done <- true
+			}()
+
+			Eventually(done).Should(Receive())
+			Expect(display.SomeValue()).To(Equal("stubbed again"))
+		})
+
+		It("does not block stubbing again with argument matchers", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenBlockUntil(make(chan struct{}))
+
+			done := make(chan bool, 1)
+			go func() {
+				When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("unblocked")
+				done <- true
+			}()
+
+			Eventually(done).Should(Receive())
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("unblocked"))
+		})
+
+		It("does not block stubbing again after ThenBlockUntilContextDone", func() {
+			object := NewMockObject()
+			ctx := context.Background()
+			object.Stub().ReadWithContext(ctx).ThenBlockUntilContextDone()
+
+			done := make(chan bool, 1)
+			go func() {
+				When(func() { object.ReadWithContext(ctx) }).ThenReturn([]byte("data"), nil)
+				done <- true
+			}()
+
+			Eventually(done).Should(Receive())
+			Expect(object.ReadWithContext(ctx)).To(Equal([]byte("data")))
+		})
+
+		It("fails for methods without context.Context param with ThenBlockUntilContextDone", func() {
+			When(display.SomeValue()).ThenBlockUntilContextDone()
+
+			Expect(func() { display.SomeValue() }).To(PanicWith(
+				"ThenBlockUntilContextDone() requires a context.Context param, but SomeValue() has none.",
+			))
+		})
+	})
+
+	Describe("Setting pointer params with ThenSetArg", func() {
+		var object *MockObject
+
+		BeforeEach(func() {
+			object = NewMockObject()
+		})
+
+		It("sets the pointee and chains with ThenReturn", func() {
+			When(object.Decode(AnyInterface())).ThenSetArg(0, "decoded").ThenReturn(nil)
+
+			var s string
+			Expect(object.Decode(&s)).To(Succeed())
+			Expect(s).To(Equal("decoded"))
+		})
+
+		It("sets variadic params and returns zero values without a next answer", func() {
+			object.Stub().Scan(AnyInterface(), AnyInterface()).ThenSetArg(0, 42).ThenSetArg(1, "answer")
+
+			var i int
+			var s string
+			Expect(object.Scan(&i, &s)).To(Succeed())
+			Expect(i).To(Equal(42))
+			Expect(s).To(Equal("answer"))
+		})
+
+		It("sets the zero value for nil", func() {
+			When(object.Decode(AnyInterface())).ThenSetArg(0, nil).ThenReturn(nil)
+
+			s := []string{"not nil"}
+			object.Decode(&s)
+			Expect(s).To(BeNil())
+		})
+
+		It("does not set the pointee when stubbing again", func() {
+			var s string
+			When(func() { object.Decode(&s) }).ThenSetArg(0, "decoded").ThenReturn(nil)
+
+			When(func() { object.Decode(&s) }).ThenReturn(errors.New("decoding failed"))
+
+			Expect(s).To(BeEmpty())
+			Expect(object.Decode(&s)).To(MatchError("decoding failed"))
+		})
+
+		It("fails for params that are not pointers", func() {
+			When(object.Decode(AnyInterface())).ThenSetArg(0, "decoded").ThenReturn(nil)
+
+			Expect(func() { object.Decode("not a pointer") }).To(PanicWith(
+				"ThenSetArg() requires param 0 of Decode(\"not a pointer\") to be a non-nil pointer.",
+			))
+		})
+
+		It("fails for values of the wrong type", func() {
+			When(object.Decode(AnyInterface())).ThenSetArg(0, 42).ThenReturn(nil)
+
+			var s string
+			Expect(func() { object.Decode(&s) }).To(PanicWith(
+				"ThenSetArg() cannot assign value of type int to param 0 of type *string.",
+			))
+		})
+	})
+
+	Describe("Composable matchers", func() {
+		It("stubs all values except one with NotEqString", func() {
+			When(display.MultipleParamsAndReturnValue(NotEqString("admin"), AnyInt())).ThenReturn("allowed")
+
+			Expect(display.MultipleParamsAndReturnValue("user", 1)).To(Equal("allowed"))
+			Expect(display.MultipleParamsAndReturnValue("admin", 1)).To(BeEmpty())
+		})
+
+		It("combines matchers with AllOf, AnyOf and NoneOf and registers them with the typed ...Matching variants", func() {
+			When(display.MultipleParamsAndReturnValue(StringMatching(AnyOf(Eq("a"), Eq("b"))), IntMatching(AllOf(That(func(i int) bool { return i < 3 }), NotEq(0))))).ThenReturn("matched")
+
+			Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("matched"))
+			Expect(display.MultipleParamsAndReturnValue("b", 2)).To(Equal("matched"))
+			Expect(display.MultipleParamsAndReturnValue("c", 1)).To(BeEmpty())
+			Expect(display.MultipleParamsAndReturnValue("a", 0)).To(BeEmpty())
+			Expect(display.MultipleParamsAndReturnValue("a", 3)).To(BeEmpty())
+		})
+
+		It("combines zero values without taking the matcher of another param", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntMatching(NoneOf(Eq(0))))).ThenReturn("non-zero")
+			When(display.MultipleParamsAndReturnValue(StringMatching(AnyOf(Eq("a"), Eq(""))), EqInt(0))).ThenReturn("zero")
+
+			Expect(display.MultipleParamsAndReturnValue("x", 1)).To(Equal("non-zero"))
+			Expect(display.MultipleParamsAndReturnValue("", 0)).To(Equal("zero"))
+			Expect(display.MultipleParamsAndReturnValue("x", 0)).To(BeEmpty())
+		})
+
+		It("provides And and Or as aliases of AllOf and AnyOf", func() {
+			When(display.MultipleParamsAndReturnValue(StringMatching(Or(Eq("a"), Eq("b"))), IntMatching(And(NotEq(0), NotEq(1))))).ThenReturn("matched")
+
+			Expect(display.MultipleParamsAndReturnValue("b", 2)).To(Equal("matched"))
+			Expect(display.MultipleParamsAndReturnValue("c", 2)).To(BeEmpty())
+			Expect(display.MultipleParamsAndReturnValue("a", 1)).To(BeEmpty())
+		})
+
+		It("describes combined matchers in failure messages", func() {
+			Expect(func() {
+				display.VerifyWasCalledOnce().MultipleParamsAndReturnValue(StringMatching(AnyOf(Eq("a"), NotEq("b"))), IntMatching(AllOf(NewAnyMatcher(reflect.TypeOf(0)), NotEq(0))))
+			}).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for MultipleParamsAndReturnValue(AnyOf(Eq(a), Not(Eq(b))), AllOf(Any(int), Not(Eq(0)))) does not match expectation.",
+			)))
+		})
+
+		It("reports the failing matcher", func() {
+			matcher := AllOf(NewAnyMatcher(reflect.TypeOf(0)), NotEq(0))
+			Expect(matcher.Matches(0)).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal("Expected: not Eq(0); but got: 0"))
+
+			anyOf := AnyOf(Eq("a"), Eq("b"))
+			Expect(anyOf.Matches("c")).To(BeFalse())
+			Expect(anyOf.FailureMessage()).To(Equal("Expected: any of Eq(a), Eq(b); but got: c"))
+		})
+	})
+
+	Describe("Gomega matchers as argument matchers", func() {
+		It("stubs and verifies with typed ...ThatMatches variants", func() {
+			When(display.MultipleParamsAndReturnValue(StringThatMatches(ContainSubstring("ell")), IntThatMatches(BeNumerically(">", 2)))).ThenReturn("matched")
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 3)).To(Equal("matched"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(BeEmpty())
+			display.VerifyWasCalled(Times(2)).MultipleParamsAndReturnValue(StringThatMatches(HavePrefix("He")), AnyInt())
+		})
+
+		It("uses generated ...ThatMatches variants for non-basic types", func() {
+			display.ArrayParam([]string{"a", "b", "c"})
+
+			display.VerifyWasCalledOnce().ArrayParam(SliceOfStringThatMatches(HaveLen(3)))
+		})
+
+		It("uses ArgThat for interface{} params", func() {
+			object := NewMockObject()
+			When(object.Decode(ArgThat(BeAssignableToTypeOf(&[]string{})))).ThenReturn(errors.New("slice"))
+
+			Expect(object.Decode(&[]string{})).To(MatchError("slice"))
+			Expect(object.Decode(new(string))).To(Succeed())
+		})
+
+		It("describes the Gomega matcher in failure messages", func() {
+			Expect(func() { display.VerifyWasCalledOnce().Show(StringThatMatches(HaveLen(3))) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Show(HaveLen{Count:3}) does not match expectation.",
+			)))
+		})
+
+		It("reuses the failure message of the Gomega matcher", func() {
+			matcher := Gomega(HaveLen(3))
+			Expect(matcher.Matches("ab")).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal(HaveLen(3).FailureMessage("ab")))
+			Expect(matcher.Matches(5)).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(ContainSubstring("HaveLen matcher expects a string/array/map/channel/slice"))
+		})
+
+		It("fails for matchers other than Gomega matchers", func() {
+			Expect(func() { ArgThat("not a matcher") }).To(PanicWith(
+				"ArgThat() requires a Gomega matcher or a predicate of the form func(T) bool, but got string.",
+			))
+		})
+	})
+
+	Describe("Predicate matchers", func() {
+		It("stubs and verifies with typed ...That variants", func() {
+			isEven := func(i int) bool { return i%2 == 0 }
+			When(display.MultipleParamsAndReturnValue(StringThat(func(s string) bool { return len(s) > 3 }), IntThat(isEven))).ThenReturn("matched")
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(Equal("matched"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 3)).To(BeEmpty())
+			Expect(display.MultipleParamsAndReturnValue("Hi", 2)).To(BeEmpty())
+			display.VerifyWasCalled(Times(2)).MultipleParamsAndReturnValue(AnyString(), IntThat(isEven))
+		})
+
+		It("uses generated ...That variants for non-basic types", func() {
+			display.NetHttpRequestPtrParam(&http.Request{Host: "x.com"})
+
+			display.VerifyWasCalledOnce().NetHttpRequestPtrParam(PtrToHttpRequestThat(func(r *http.Request) bool { return r.Host == "x.com" }))
+			display.VerifyWasCalled(Never()).NetHttpRequestPtrParam(PtrToHttpRequestThat(func(r *http.Request) bool { return r.Host == "y.com" }))
+		})
+
+		It("uses ArgThat with predicates for interface{} params", func() {
+			object := NewMockObject()
+			When(object.Decode(ArgThat(func(s *string) bool { return s != nil }))).ThenReturn(errors.New("string"))
+
+			Expect(object.Decode(new(string))).To(MatchError("string"))
+			Expect(object.Decode((*string)(nil))).To(Succeed())
+			Expect(object.Decode(new(int))).To(Succeed())
+			Expect(object.Decode(nil)).To(Succeed())
+		})
+
+		It("describes the predicate in failure messages", func() {
+			Expect(func() { display.VerifyWasCalledOnce().Show(StringThat(func(string) bool { return true })) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Show(That(func(string) bool)) does not match expectation.",
+			)))
+
+			matcher := NewPredicateMatcher(func(i int) bool { return i > 0 })
+			Expect(matcher.Matches(0)).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal("Expected: value satisfying func(int) bool; but got: 0"))
+		})
+
+		It("keeps earlier stubbings with the same predicate, since funcs never compare equal", func() {
+			isEven := func(i int) bool { return i%2 == 0 }
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntThat(isEven))).ThenReturn("first")
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntThat(isEven))).ThenReturn("second")
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(Equal("second"))
+			Expect(func() { VerifyAllStubbingsUsed(display) }).To(PanicWithMessageTo(HavePrefix(
+				"Found unused stubbings:\n\tMultipleParamsAndReturnValue(Any(string), That(func(int) bool)) stubbed at ",
+			)))
+		})
+
+		It("fails for funcs other than predicates at registration time", func() {
+			Expect(func() { ArgThat(func(a, b int) bool { return true }) }).To(PanicWith(
+				"Must provide a predicate of the form func(T) bool, but got func(int, int) bool",
+			))
+			Expect(func() { ArgThat(func(int) int { return 0 }) }).To(PanicWith(
+				"Must provide a predicate of the form func(T) bool, but got func(int) int",
+			))
+			Expect(func() { ArgThat(42) }).To(PanicWith(
+				"ArgThat() requires a Gomega matcher or a predicate of the form func(T) bool, but got int.",
+			))
+		})
+	})
+
+	Describe("String matchers", func() {
+		It("matches strings by content", func() {
+			When(display.MultipleParamsAndReturnValue(StringContaining("SELECT"), AnyInt())).ThenReturn("contains")
+			When(display.MultipleParamsAndReturnValue(StringHasPrefix("INSERT"), AnyInt())).ThenReturn("prefix")
+			When(display.MultipleParamsAndReturnValue(StringHasSuffix(";"), AnyInt())).ThenReturn("suffix")
+			When(display.MultipleParamsAndReturnValue(StringMatchingRegex(`^DELETE FROM \w+$`), AnyInt())).ThenReturn("regex")
+			When(display.MultipleParamsAndReturnValue(StringEqualFold("commit"), AnyInt())).ThenReturn("equal fold")
+
+			Expect(display.MultipleParamsAndReturnValue("x SELECT y", 0)).To(Equal("contains"))
+			Expect(display.MultipleParamsAndReturnValue("INSERT x", 0)).To(Equal("prefix"))
+			Expect(display.MultipleParamsAndReturnValue("x;", 0)).To(Equal("suffix"))
+			Expect(display.MultipleParamsAndReturnValue("DELETE FROM users", 0)).To(Equal("regex"))
+			Expect(display.MultipleParamsAndReturnValue("COMMIT", 0)).To(Equal("equal fold"))
+			Expect(display.MultipleParamsAndReturnValue("ROLLBACK", 0)).To(BeEmpty())
+		})
+
+		It("matches byte slices by content", func() {
+			broker := NewMockBroker()
+			broker.Publish("logs", []byte("level=error msg=Timeout"))
+
+			broker.VerifyWasCalledOnce().Publish(AnyString(), BytesContaining([]byte("msg=")))
+			broker.VerifyWasCalledOnce().Publish(AnyString(), BytesHasPrefix([]byte("level=error")))
+			broker.VerifyWasCalledOnce().Publish(AnyString(), BytesHasSuffix([]byte("Timeout")))
+			broker.VerifyWasCalledOnce().Publish(AnyString(), BytesMatchingRegex(`msg=\w+`))
+			broker.VerifyWasCalledOnce().Publish(AnyString(), BytesEqualFold([]byte("LEVEL=ERROR MSG=TIMEOUT")))
+			broker.VerifyWasCalled(Never()).Publish(AnyString(), BytesContaining([]byte("warn")))
+		})
+
+		It("replaces stubbings when stubbing again with equal matchers", func() {
+			When(display.MultipleParamsAndReturnValue(StringContaining("a"), AnyInt())).ThenReturn("first")
+			When(display.MultipleParamsAndReturnValue(StringContaining("a"), AnyInt())).ThenReturn("second")
+			When(display.MultipleParamsAndReturnValue(StringMatchingRegex(`^\d+$`), AnyInt())).ThenReturn("first")
+			When(display.MultipleParamsAndReturnValue(StringMatchingRegex(`^\d+$`), AnyInt())).ThenReturn("second")
+
+			Expect(display.MultipleParamsAndReturnValue("abc", 0)).To(Equal("second"))
+			Expect(display.MultipleParamsAndReturnValue("123", 0)).To(Equal("second"))
+			VerifyAllStubbingsUsed(display)
+		})
+
+		It("shows the matchers and actual values in failure messages", func() {
+			display.Show("Hi there")
+
+			Expect(func() { display.VerifyWasCalledOnce().Show(StringHasPrefix("Hello")) }).To(PanicWith(
+				"Mock invocation count for Show(StringHasPrefix(\"Hello\")) " +
+					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
+					"\tClosest invocation was:\n" +
+					"\tShow(\"Hi there\")\n" +
+					"\t\tArgument 0: Expected: StringHasPrefix(\"Hello\"); but got: \"Hi there\"\n\n" +
+					"\tBut other interactions with this mock were:\n" +
+					"\tShow(\"Hi there\")\n",
+			))
+		})
+
+		It("fails for invalid regexes at registration time", func() {
+			Expect(func() { StringMatchingRegex("(") }).To(PanicWith(
+				"StringMatchingRegex() got invalid regex \"(\": error parsing regexp: missing closing ): `(`",
+			))
+		})
+	})
+
+	Describe("Numeric matchers", func() {
+		It("matches numbers by comparison", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntGreaterThan(10))).ThenReturn("greater")
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntLessThan(0))).ThenReturn("less")
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntBetween(3, 5))).ThenReturn("between")
+
+			Expect(display.MultipleParamsAndReturnValue("", 11)).To(Equal("greater"))
+			Expect(display.MultipleParamsAndReturnValue("", 10)).To(BeEmpty())
+			Expect(display.MultipleParamsAndReturnValue("", -1)).To(Equal("less"))
+			Expect(display.MultipleParamsAndReturnValue("", 3)).To(Equal("between"))
+			Expect(display.MultipleParamsAndReturnValue("", 5)).To(Equal("between"))
+			Expect(display.MultipleParamsAndReturnValue("", 6)).To(BeEmpty())
+		})
+
+		It("matches floats within epsilon", func() {
+			display.FloatParam(0.3)
+
+			display.VerifyWasCalledOnce().FloatParam(Float32CloseTo(0.1+0.2, 0.0001))
+			display.VerifyWasCalled(Never()).FloatParam(Float32CloseTo(0.4, 0.05))
+		})
+
+		It("does not match NaN", func() {
+			display.FloatParam(float32(math.NaN()))
+
+			display.VerifyWasCalled(Never()).FloatParam(Float32Between(-1, 1))
+			display.VerifyWasCalled(Never()).FloatParam(Float32CloseTo(0, 1))
+		})
+
+		It("replaces stubbings when stubbing again with equal matchers", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntGreaterThan(5))).ThenReturn("first")
+			When(display.MultipleParamsAndReturnValue(AnyString(), IntGreaterThan(5))).ThenReturn("second")
+
+			Expect(display.MultipleParamsAndReturnValue("", 6)).To(Equal("second"))
+			VerifyAllStubbingsUsed(display)
+		})
+
+		It("shows the comparison in failure messages", func() {
+			display.FloatParam(1.5)
+
+			Expect(func() { display.VerifyWasCalledOnce().FloatParam(Float32Between(2, 3)) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for FloatParam(Float32Between(2, 3)) does not match expectation.",
+			)))
+		})
+	})
+
+	Describe("Collection matchers", func() {
+		It("matches slices by elements and length", func() {
+			display.ArrayParam([]string{"a", "b", "c"})
+
+			display.VerifyWasCalledOnce().ArrayParam(SliceOfStringContaining("b"))
+			display.VerifyWasCalledOnce().ArrayParam(SliceOfStringContainingInAnyOrder("c", "a", "b"))
+			display.VerifyWasCalledOnce().ArrayParam(SliceOfStringOfLen(3))
+			display.VerifyWasCalled(Never()).ArrayParam(SliceOfStringContaining("d"))
+			display.VerifyWasCalled(Never()).ArrayParam(SliceOfStringContainingInAnyOrder("a", "b"))
+			display.VerifyWasCalled(Never()).ArrayParam(SliceOfStringContainingInAnyOrder("a", "a", "b"))
+		})
+
+		It("matches maps by keys and entries", func() {
+			display.MapOfStringToInterfaceParam(map[string]interface{}{"retries": 3, "verbose": nil})
+
+			display.VerifyWasCalledOnce().MapOfStringToInterfaceParam(MapOfStringToInterfaceHasKey("verbose"))
+			display.VerifyWasCalledOnce().MapOfStringToInterfaceParam(MapOfStringToInterfaceHasEntry("retries", 3))
+			display.VerifyWasCalledOnce().MapOfStringToInterfaceParam(MapOfStringToInterfaceHasEntry("verbose", nil))
+			display.VerifyWasCalled(Never()).MapOfStringToInterfaceParam(MapOfStringToInterfaceHasKey("timeout"))
+			display.VerifyWasCalled(Never()).MapOfStringToInterfaceParam(MapOfStringToInterfaceHasEntry("retries", 4))
+		})
+
+		It("works through reflection on any slice or map type", func() {
+			object := NewMockObject()
+			object.Decode([3]int{1, 2, 3})
+			object.Decode([]int(nil))
+			object.Decode(map[string]int{"1": 1})
+			object.Decode(map[error]int{nil: 1})
+
+			object.VerifyWasCalledOnce().Decode(SliceContaining(2))
+			object.VerifyWasCalledOnce().Decode(SliceContainingInAnyOrder(3, 1, 2))
+			object.VerifyWasCalledOnce().Decode(SliceOfLen(0))
+			object.VerifyWasCalledOnce().Decode(MapHasKey(nil))
+			object.VerifyWasCalledOnce().Decode(MapHasEntry("1", 1))
+			object.VerifyWasCalled(Never()).Decode(MapHasKey(1))
+			object.VerifyWasCalled(Never()).Decode(SliceContaining("1"))
+		})
+
+		It("replaces stubbings when stubbing again with equal matchers", func() {
+			object := NewMockObject()
+			When(object.Decode(SliceContaining(1))).ThenReturn(errors.New("first"))
+			When(object.Decode(SliceContaining(1))).ThenReturn(errors.New("second"))
+			When(object.Decode(MapHasKey("k"))).ThenReturn(errors.New("first"))
+			When(object.Decode(MapHasKey("k"))).ThenReturn(errors.New("second"))
+			When(object.Decode(MapHasEntry("e", 1))).ThenReturn(errors.New("first"))
+			When(object.Decode(MapHasEntry("e", 1))).ThenReturn(errors.New("second"))
+
+			Expect(object.Decode([]int{1})).To(MatchError("second"))
+			Expect(object.Decode(map[string]int{"k": 0})).To(MatchError("second"))
+			Expect(object.Decode(map[string]int{"e": 1})).To(MatchError("second"))
+			VerifyAllStubbingsUsed(object)
+		})
+
+		It("describes the matchers in failure messages", func() {
+			object := NewMockObject()
+			object.Decode([]string{"a"})
+
+			Expect(func() { object.VerifyWasCalledOnce().Decode(SliceContainingInAnyOrder("a", "b")) }).To(PanicWithMessageTo(ContainSubstring(
+				`Argument 0: Expected: SliceContainingInAnyOrder("a", "b"); but got: []string{"a"}`,
+			)))
+			Expect(func() { display.VerifyWasCalledOnce().MapParam(MapOfStringToHttpRequestHasKey("x")) }).To(PanicWithMessageTo(HavePrefix(
+				`Mock invocation count for MapParam(MapHasKey("x")) does not match expectation.`,
+			)))
+		})
+	})
+
+	Describe("Struct matchers", func() {
+		var request *http.Request
+
+		BeforeEach(func() {
+			request = &http.Request{Method: "GET", Host: "x.com", URL: &url.URL{Path: "/users"}, ContentLength: 42}
+		})
+
+		It("matches only the given, possibly nested fields", func() {
+			display.NetHttpRequestPtrParam(request)
+
+			display.VerifyWasCalledOnce().NetHttpRequestPtrParam(PtrToHttpRequestWithFields(map[string]interface{}{
+				"Host":     "x.com",
+				"URL.Path": "/users",
+			}))
+			display.VerifyWasCalled(Never()).NetHttpRequestPtrParam(PtrToHttpRequestWithFields(map[string]interface{}{
+				"Host":     "x.com",
+				"URL.Path": "/groups",
+			}))
+		})
+
+		It("allows matchers as field values", func() {
+			display.NetHttpRequestParam(*request)
+
+			display.VerifyWasCalledOnce().NetHttpRequestParam(HttpRequestWithFields(map[string]interface{}{
+				"Method":        AnyOf(Eq("GET"), Eq("HEAD")),
+				"ContentLength": That(BeNumerically(">", 0)),
+			}))
+		})
+
+		It("reports matchers registered by field values", func() {
+			Expect(func() {
+				StructWithFields(map[string]interface{}{"Host": EqString("x.com"), "Method": "GET"})
+				When(display.SomeValue()).ThenReturn("value")
+			}).To(PanicWithMessageTo(HaveSuffix(
+				"0 matchers expected, 1 recorded.\n\n" +
+					"StructWithFields() got zero values for fields Host. If they have been created by matcher factories like EqString(),\n" +
+					"use matchers that do not register themselves, like Eq(), NotEq() or That(), instead.",
+			)))
+
+			When(display.SomeValue()).ThenReturn("value")
+			Expect(display.SomeValue()).To(Equal("value"))
+		})
+
+		It("reports promoted fields behind a nil embedded pointer as mismatch", func() {
+			type Inner struct{ City string }
+			type Outer struct{ *Inner }
+			matcher := StructWithFields(map[string]interface{}{"City": "x"})
+
+			Expect(matcher.Matches(Outer{})).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal("Mismatching fields:\n\t\tCity: cannot access field City of pegomock_test.Outer through nil embedded pointer"))
+			Expect(matcher.Matches(Outer{&Inner{City: "x"}})).To(BeTrue())
+		})
+
+		It("lists each mismatching field in the failure message", func() {
+			matcher := StructWithFields(map[string]interface{}{
+				"Host":          "y.com",
+				"Method":        "GET",
+				"ContentLength": NewPredicateMatcher(func(n int64) bool { return n == 0 }),
+				"URL.Path":      "/users",
+				"TLS.Version":   uint16(0),
+				"Nonexistent":   1,
+			})
+
+			Expect(matcher.Matches(request)).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal("Mismatching fields:\n" +
+				"\t\tContentLength: Expected: value satisfying func(int64) bool; but got: 42\n" +
+				"\t\tHost: Expected: \"y.com\"; but got: \"x.com\"\n" +
+				"\t\tNonexistent: http.Request has no field Nonexistent\n" +
+				"\t\tTLS.Version: cannot access field Version of nil *tls.ConnectionState"))
+			Expect(matcher.String()).To(Equal(`StructWithFields(ContentLength: That(func(int64) bool), Host: "y.com", Method: "GET", ` +
+				`Nonexistent: 1, TLS.Version: 0x0, URL.Path: "/users")`))
+		})
+
+		It("does not match values other than structs", func() {
+			matcher := StructWithFields(map[string]interface{}{"Host": "x.com"})
+			Expect(matcher.Matches(nil)).To(BeFalse())
+			Expect(matcher.Matches((*http.Request)(nil))).To(BeFalse())
+			Expect(matcher.Matches("x.com")).To(BeFalse())
+			Expect(matcher.FailureMessage()).To(Equal("Mismatching fields:\n\t\tHost: cannot access field Host of non-struct string"))
+		})
+	})
+
+	Describe("Pluggable equality", func() {
+		var withMonotonicClock, withoutMonotonicClock time.Time
+
+		BeforeEach(func() {
+			withMonotonicClock = time.Now()
+			withoutMonotonicClock = withMonotonicClock.Round(0)
+		})
+
+		AfterEach(ResetEquality)
+
+		It("uses reflect.DeepEqual by default", func() {
+			display.UseTime(withMonotonicClock)
+
+			display.VerifyWasCalled(Never()).UseTime(withoutMonotonicClock)
+		})
+
+		It("uses reflect.DeepEqual for values that no registered equality applies to", func() {
+			RegisterEquality(func(a, b lengthForEquality) bool { return a.value == b.value })
+			RegisterCmpOptions(cmp.Comparer(func(a, b *url.URL) bool { return a.String() == b.String() }))
+			display := NewMockDisplay(WithEquality(func(a, b versionForEquality) bool { return a.major == b.major }))
+			display.UseTime(withMonotonicClock)
+			display.InterfaceParam(struct{ Time time.Time }{withMonotonicClock})
+
+			display.VerifyWasCalled(Never()).UseTime(withoutMonotonicClock)
+			display.VerifyWasCalled(Never()).InterfaceParam(struct{ Time time.Time }{withoutMonotonicClock})
+		})
+
+		It("uses equality funcs of the mock for raw values and Eq matchers", func() {
+			display := NewMockDisplay(WithEquality(func(a, b time.Time) bool { return a.Equal(b) }))
+			display.UseTime(withMonotonicClock)
+
+			display.VerifyWasCalledOnce().UseTime(withoutMonotonicClock)
+			display.VerifyWasCalledOnce().UseTime(EqTimeTime(withoutMonotonicClock))
+		})
+
+		It("uses equality funcs of the mock for auto-created matchers in stubbings", func() {
+			object := NewMockObject(WithEquality(func(a, b *string) bool { return *a == *b }))
+			s := "x"
+			When(object.Decode(&s)).ThenReturn(errors.New("stubbed"))
+
+			t := "x"
+			Expect(object.Decode(&t)).To(MatchError("stubbed"))
+		})
+
+		It("uses equality funcs in combined, collection and struct matchers", func() {
+			display := NewMockDisplay(WithEquality(func(a, b time.Time) bool { return a.Equal(b) }))
+			display.UseTime(withMonotonicClock)
+			display.MapOfStringToInterfaceParam(map[string]interface{}{"time": withMonotonicClock})
+			display.InterfaceParam(struct{ Time time.Time }{withMonotonicClock})
+
+			RegisterMatcher(NotEq(withoutMonotonicClock))
+			display.VerifyWasCalled(Never()).UseTime(time.Time{})
+			display.VerifyWasCalledOnce().MapOfStringToInterfaceParam(MapOfStringToInterfaceHasEntry("time", withoutMonotonicClock))
+			RegisterMatcher(StructWithFields(map[string]interface{}{"Time": withoutMonotonicClock}))
+			display.VerifyWasCalledOnce().InterfaceParam(nil)
+		})
+
+		It("uses cmp options of the mock and compares unexported fields", func() {
+			display := NewMockDisplay(WithCmpOptions(cmp.Comparer(func(a, b *url.URL) bool { return a.String() == b.String() })))
+			display.NetHttpRequestParam(http.Request{Host: "x.com", URL: &url.URL{Path: "/users"}})
+
+			display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "x.com", URL: &url.URL{Path: "/users"}})
+			display.VerifyWasCalled(Never()).NetHttpRequestParam(http.Request{Host: "y.com", URL: &url.URL{Path: "/users"}})
+		})
+
+		It("uses globally registered equality funcs, unless the mock has its own", func() {
+			RegisterEquality(func(a, b versionForEquality) bool { return a.major == b.major })
+			object := NewMockObject()
+			object.Decode(versionForEquality{major: 1, minor: 2})
+
+			object.VerifyWasCalledOnce().Decode(versionForEquality{major: 1, minor: 3})
+
+			object = NewMockObject(WithEquality(func(a, b versionForEquality) bool { return a == b }))
+			object.Decode(versionForEquality{major: 1, minor: 2})
+
+			object.VerifyWasCalled(Never()).Decode(versionForEquality{major: 1, minor: 3})
+		})
+
+		It("applies global registrations to mocks that have compared values before", func() {
+			object := NewMockObject()
+			object.Decode(lengthForEquality{value: 1, unit: "m"})
+			object.VerifyWasCalled(Never()).Decode(lengthForEquality{value: 1, unit: "cm"})
+
+			RegisterEquality(func(a, b lengthForEquality) bool { return a.value == b.value })
+
+			object.VerifyWasCalledOnce().Decode(lengthForEquality{value: 1, unit: "cm"})
+		})
+
+		It("still compares unexported fields of other types after a global registration", func() {
+			RegisterEquality(func(a, b lengthForEquality) bool { return a.value == b.value })
+			object := NewMockObject()
+			object.Decode(versionForEquality{major: 1, minor: 2})
+			object.Decode(http.Request{Host: "x.com"})
+
+			object.VerifyWasCalledOnce().Decode(http.Request{Host: "x.com"})
+			object.VerifyWasCalled(Never()).Decode(http.Request{Host: "y.com"})
+			object.VerifyWasCalled(Never()).Decode(versionForEquality{major: 2, minor: 2})
+		})
+
+		It("removes global registrations with ResetEquality", func() {
+			RegisterEquality(func(a, b versionForEquality) bool { return a.major == b.major })
+			object := NewMockObject()
+			object.Decode(versionForEquality{major: 1, minor: 2})
+			object.VerifyWasCalledOnce().Decode(versionForEquality{major: 1, minor: 3})
+
+			ResetEquality()
+
+			object.VerifyWasCalled(Never()).Decode(versionForEquality{major: 1, minor: 3})
+		})
+
+		It("fails for funcs other than func(T, T) bool", func() {
+			Expect(func() { WithEquality(func(a time.Time, b *time.Time) bool { return true }) }).To(PanicWith(
+				"WithEquality() requires a func(T, T) bool, but got func(time.Time, *time.Time) bool.",
+			))
+			Expect(func() { RegisterEquality("not a func") }).To(PanicWith(
+				"RegisterEquality() requires a func(T, T) bool, but got string.",
+			))
+		})
+	})
+
+	Describe("Invoking callback params with ThenInvokeArg", func() {
+		var broker *MockBroker
+
+		BeforeEach(func() {
+			broker = NewMockBroker()
+		})
+
+		It("calls the callback synchronously before the next answer", func() {
+			var received []string
+			broker.Stub().Subscribe(AnyString(), AnyFuncOfStringAndInt()).
+				ThenInvokeArg(1, "hello", 1).
+				ThenReturn(nil)
+
+			Expect(broker.Subscribe("news", func(message string, attempt int) {
+				received = append(received, fmt.Sprint(message, attempt))
+			})).To(Succeed())
+			Expect(received).To(Equal([]string{"hello1"}))
+		})
+
+		It("calls the callback in a new goroutine with ThenInvokeArgAsync", func() {
+			received := make(chan string, 1)
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArgAsync(1, "hello", 2)
+
+			Expect(broker.Subscribe("news", func(message string, attempt int) { received <- fmt.Sprint(message, attempt) })).To(Succeed())
+			Eventually(received).Should(Receive(Equal("hello2")))
+		})
+
+		It("passes zero values for nil args", func() {
+			var received string
+			var receivedAttempt = -1
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArg(1, nil, nil)
+
+			broker.Subscribe("news", func(message string, attempt int) { received, receivedAttempt = message, attempt })
+			Expect(received).To(BeEmpty())
+			Expect(receivedAttempt).To(BeZero())
+		})
+
+		It("does not call the callback when stubbing again", func() {
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArg(1, "hello", 1)
+
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenReturn(errors.New("closed"))
+
+			Expect(broker.Subscribe("news", func(string, int) {})).To(MatchError("closed"))
+		})
+
+		It("gives back the typed callback via captor", func() {
+			var received string
+			broker.Subscribe("news", func(message string, attempt int) { received = message })
+
+			_, handler := broker.VerifyWasCalledOnce().Subscribe(EqString("news"), AnyFuncOfStringAndInt()).GetCapturedArguments()
+			handler("captured", 1)
+			Expect(received).To(Equal("captured"))
+		})
+
+		It("fails for params that are not funcs", func() {
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArg(0, "hello", 1)
+
+			Expect(func() { broker.Subscribe("news", func(string, int) {}) }).To(PanicWithMessageTo(
+				MatchRegexp(`^ThenInvokeArg\(\) requires param 0 of Subscribe\("news", \(func\(string, int\)\)\(0x[0-9a-f]+\)\) to be a non-nil func\.$`),
+			))
+		})
+
+		It("fails for the wrong number of args", func() {
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArg(1, "hello")
+
+			Expect(func() { broker.Subscribe("news", func(string, int) {}) }).To(PanicWith(
+				"ThenInvokeArg() got 1 args, but func(string, int) takes 2.",
+			))
+		})
+
+		It("fails for args of the wrong type", func() {
+			When(broker.Subscribe(AnyString(), AnyFuncOfStringAndInt())).ThenInvokeArgAsync(1, "hello", "1")
+
+			Expect(func() { broker.Subscribe("news", func(string, int) {}) }).To(PanicWith(
+				"ThenInvokeArgAsync() cannot pass arg 1 of type string to func(string, int).",
+			))
+		})
+	})
+
+	Describe("Typed stubbing", func() {
+		It("stubs with the exact return types of the method", func() {
+			display.Stub().MultipleParamsAndReturnValue("Hello", 1).ThenReturn("stubbed")
+			display.Stub().MultipleValues().ThenReturn("a", 1, 2)
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("stubbed"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(Equal(""))
+			s, i, f := display.MultipleValues()
+			Expect(s).To(Equal("a"))
+			Expect(i).To(Equal(1))
+			Expect(f).To(Equal(float32(2)))
+		})
+
+		It("supports argument matchers", func() {
+			display.Stub().MultipleParamsAndReturnValue(AnyString(), EqInt(1)).ThenReturn("stubbed")
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("stubbed"))
+			Expect(display.MultipleParamsAndReturnValue("Bye", 1)).To(Equal("stubbed"))
+		})
+
+		It("supports consecutive answers", func() {
+			display.Stub().SomeValue().ThenReturn("first").ThenReturn("second").ThenPanic("no more")
+
+			Expect(display.SomeValue()).To(Equal("first"))
+			Expect(display.SomeValue()).To(Equal("second"))
+			Expect(func() { display.SomeValue() }).To(PanicWith("no more"))
+		})
+
+		It("supports methods without return values and variadic params", func() {
+			display.Stub().VariadicParam("one", "two").ThenPanic("stubbed")
+
+			Expect(func() { display.VariadicParam("one", "two") }).To(PanicWith("stubbed"))
+			Expect(func() { display.VariadicParam("one") }).NotTo(Panic())
+		})
+
+		It("does not invoke the mock", func() {
+			display := NewMockDisplay(WithStrictness(Strict), WithDelegate(&realDisplay{}))
+
+			display.Stub().SomeValue().ThenReturn("stubbed")
+
+			VerifyZeroInteractions(display)
+		})
+
+		It("reports the location of unused typed stubbings", func() {
+			display.Stub().SomeValue().ThenReturn("stubbed")
+
+			Expect(InterceptMockFailures(func() { VerifyAllStubbingsUsed(display) })).To(ConsistOf(
+				MatchRegexp(`SomeValue\(\) stubbed at .*dsl_test.go:\d+`),
+			))
+		})
+
+		It("calls typed callbacks with ThenDo", func() {
+			display.Stub().MultipleParamsAndReturnValue(AnyString(), AnyInt()).ThenDo(func(s string, i int) string {
+				return strings.Repeat(s, i)
+			})
+
+			Expect(display.MultipleParamsAndReturnValue("ab", 2)).To(Equal("abab"))
+		})
+
+		It("calls typed callbacks with variadic and nil params", func() {
+			var received []string
+			display.Stub().NormalAndVariadicParam(AnyString(), AnyInt(), AnyString(), AnyString()).ThenDo(func(s string, i int, v ...string) {
+				received = append([]string{s}, v...)
+			})
+			var receivedError error = errors.New("not called")
+			display.Stub().ErrorParam(AnyError()).ThenDo(func(e error) { receivedError = e })
+
+			display.NormalAndVariadicParam("a", 1, "b", "c")
+			display.ErrorParam(nil)
+
+			Expect(received).To(Equal([]string{"a", "b", "c"}))
+			Expect(receivedError).To(BeNil())
+		})
+
+		It("works alongside untyped stubbing", func() {
+			display.Stub().MultipleParamsAndReturnValue("Hello", 1).ThenReturn("typed")
+			When(display.MultipleParamsAndReturnValue("Hello", 2)).ThenReturn("untyped")
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("typed"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(Equal("untyped"))
+		})
+	})
+
+	Describe("Spying on a real implementation with WithDelegate", func() {
+		var (
+			real *realDisplay
+			spy  *MockDisplay
+		)
+
+		BeforeEach(func() {
+			real = &realDisplay{}
+			spy = NewMockDisplay(WithDelegate(real))
+		})
+
+		It("forwards unstubbed invocations to the delegate and records them", func() {
+			spy.Show("Hello")
+			Expect(spy.MultipleParamsAndReturnValue("Hello", 3)).To(Equal("Hello Hello Hello"))
+
+			Expect(real.shown).To(ConsistOf("Hello"))
+			spy.VerifyWasCalledOnce().Show("Hello")
+			spy.VerifyWasCalledOnce().MultipleParamsAndReturnValue("Hello", 3)
+		})
+
+		It("forwards variadic params", func() {
+			spy.VariadicParam("one", "two")
+
+			Expect(real.shown).To(Equal([]string{"one", "two"}))
+		})
+
+		It("uses stubbings instead of the delegate", func() {
+			When(spy.MultipleParamsAndReturnValue(AnyString(), EqInt(1))).ThenReturn("stubbed")
+
+			Expect(spy.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("stubbed"))
+			Expect(spy.MultipleParamsAndReturnValue("Hello", 2)).To(Equal("Hello Hello"))
+		})
+
+		It("does not forward invocations that are part of a stubbing", func() {
+			When(func() { spy.Show("Hello") }).ThenReturn()
+
+			Expect(real.shown).To(BeEmpty())
+		})
+
+		It("calls the real method after other stubbed values with ThenCallRealMethod", func() {
+			When(spy.SomeValue()).ThenReturn("stubbed").ThenCallRealMethod()
+
+			Expect(spy.SomeValue()).To(Equal("stubbed"))
+			Expect(spy.SomeValue()).To(Equal("real value"))
+		})
+
+		It("names the method if the delegate does not implement it", func() {
+			Expect(func() { spy.Flash("Hello", 3) }).To(PanicWith(
+				"Calling the real method requires the delegate to implement Flash(_param0 string, _param1 int).",
+			))
+		})
+
+		It("does not allow ThenCallRealMethod for mocks without delegate", func() {
+			Expect(func() { When(display.SomeValue()).ThenCallRealMethod() }).To(PanicWith(
+				"ThenCallRealMethod() requires a mock created with WithDelegate.",
+			))
+		})
+	})
+
+	Describe("Default answers", func() {
+		var displayType = reflect.TypeOf((*test_interface.Display)(nil)).Elem()
+
+		It("returns zero values by default", func() {
+			stringChan, errorChan := display.ChanReturnValues()
+
+			Expect(stringChan).To(BeNil())
+			Expect(errorChan).To(BeNil())
+		})
+
+		It("uses a custom default answer for unstubbed invocations only", func() {
+			display := NewMockDisplay(WithDefaultAnswer(func(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
+				return ReturnValues{"default for " + methodName}
+			}))
+			When(display.MultipleParamsAndReturnValue("Hello", 1)).ThenReturn("stubbed")
+
+			Expect(display.SomeValue()).To(Equal("default for SomeValue"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("stubbed"))
+			Expect(display.MultipleParamsAndReturnValue("Hello", 2)).To(Equal("default for MultipleParamsAndReturnValue"))
+		})
+
+		It("returns zero values with ReturnZeroValues", func() {
+			display := NewMockDisplay(WithDefaultAnswer(ReturnZeroValues))
+
+			Expect(display.MultipleValues()).To(BeZero())
+			stringChan, errorChan := display.ChanReturnValues()
+			Expect(stringChan).To(BeNil())
+			Expect(errorChan).To(BeNil())
+		})
+
+		It("returns non-nil empty slices, maps and channels with ReturnEmptyValues", func() {
+			display := NewMockDisplay(WithDefaultAnswer(ReturnEmptyValues))
+
+			stringChan, errorChan := display.ChanReturnValues()
+			Expect(stringChan).NotTo(BeNil())
+			Expect(errorChan).NotTo(BeNil())
+
+			result := GetGenericMockFrom(display).Invoke("SomeMethod", nil, []reflect.Type{
+				reflect.TypeOf([]string{}), reflect.TypeOf(map[string]int{}), reflect.TypeOf(""),
+			})
+			Expect(result[0]).To(SatisfyAll(gomega.Not(BeNil()), BeEmpty()))
+			Expect(result[1]).To(SatisfyAll(gomega.Not(BeNil()), BeEmpty()))
+			Expect(result[2]).To(Equal(""))
+		})
+
+		It("returns mocks for interface return types with ReturnMocks", func() {
+			fail := func(message string, callerSkip ...int) { panic("nested: " + message) }
+			display := NewMockDisplay(WithDefaultAnswer(ReturnMocks), WithFailHandler(fail))
+
+			result := GetGenericMockFrom(display).Invoke("SomeMethod", nil, []reflect.Type{displayType})
+
+			Expect(result[0]).To(BeAssignableToTypeOf(&MockDisplay{}))
+			nestedDisplay := result[0].(*MockDisplay)
+			Expect(nestedDisplay).NotTo(BeIdenticalTo(display))
+			Expect(func() { nestedDisplay.VerifyWasCalledOnce().Show("Hello") }).To(PanicWithMessageTo(HavePrefix("nested: ")))
+		})
+
+		It("does not return mocks for error and empty interfaces with ReturnMocks", func() {
+			display := NewMockDisplay(WithDefaultAnswer(ReturnMocks))
+
+			Expect(display.ErrorReturnValue()).To(BeNil())
+			Expect(display.InterfaceReturnValue()).To(BeNil())
+		})
+
+		It("names the method if a custom default answer returns the wrong number of values", func() {
+			display := NewMockDisplay(WithDefaultAnswer(func(string, []Param, []reflect.Type) ReturnValues { return nil }))
+
+			Expect(func() { display.SomeValue() }).To(PanicWith(
+				"Default answer for SomeValue() returned invalid values: Different number of return values",
+			))
+		})
+
+		It("names the method if a custom default answer returns values of the wrong type", func() {
+			display := NewMockDisplay(WithDefaultAnswer(func(string, []Param, []reflect.Type) ReturnValues { return ReturnValues{42} }))
+
+			Expect(func() { display.SomeValue() }).To(PanicWith(
+				"Default answer for SomeValue() returned invalid values: Return value of type int not assignable to return type string",
+			))
+		})
+
+		It("uses the default answer after failing for strict mocks", func() {
+			display := NewMockDisplay(WithStrictness(Strict), WithDefaultAnswer(ReturnEmptyValues),
+				WithFailHandler(func(message string, callerSkip ...int) {}))
+
+			stringChan, _ := display.ChanReturnValues()
+
+			Expect(stringChan).NotTo(BeNil())
+		})
+	})
+
+	Describe("Deep stubs", func() {
+		var storage *MockStorage
+
+		BeforeEach(func() {
+			storage = NewMockStorage(WithDeepStubs())
+		})
+
+		It("allows to stub a chain of invocations in one go", func() {
+			When(storage.Bucket("x").Object("y").Read()).ThenReturn([]byte("data"), nil)
+
+			data, e := storage.Bucket("x").Object("y").Read()
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(data).To(Equal([]byte("data")))
+		})
+
+		It("returns the same mock for invocations with equal params", func() {
+			Expect(storage.Bucket("x")).To(BeIdenticalTo(storage.Bucket("x")))
+			Expect(storage.Bucket("x")).NotTo(BeIdenticalTo(storage.Bucket("y")))
+		})
+
+		It("works within When(func() { ... })", func() {
+			When(func() { storage.Bucket("x").Object("y").Read() }).ThenReturn([]byte("data"), nil)
+
+			data, _ := storage.Bucket("x").Object("y").Read()
+
+			Expect(data).To(Equal([]byte("data")))
+		})
+
+		It("allows argument matchers in the last invocation of the chain", func() {
+			When(storage.Bucket("x").Object(AnyString())).ThenReturn(nil)
+
+			Expect(storage.Bucket("x").Object("y")).To(BeNil())
+		})
+
+		It("does not record the invocations of a stubbed chain", func() {
+			When(storage.Bucket("x").Object("y").Read()).ThenReturn([]byte("data"), nil)
+			When(func() { storage.Bucket("x").Object("z").Read() }).ThenReturn([]byte("other data"), nil)
+
+			bucket := storage.Bucket("x").(*MockBucket)
+			object := bucket.Object("y").(*MockObject)
+
+			storage.VerifyWasCalledOnce().Bucket("x")
+			bucket.VerifyWasCalledOnce().Object("y")
+			VerifyNoMoreInteractions(storage, bucket, object)
+		})
+
+		It("does not report deep stubs as unused stubbings", func() {
+			storage.Bucket("x")
+
+			Expect(InterceptMockFailures(func() { VerifyAllStubbingsUsed(storage) })).To(BeEmpty())
+		})
+
+		It("makes nested mocks report failures through the fail handler of the mock", func() {
+			fail := func(message string, callerSkip ...int) { panic("nested: " + message) }
+			storage := NewMockStorage(WithDeepStubs(), WithFailHandler(fail))
+
+			Expect(func() { storage.Bucket("x").(*MockBucket).VerifyWasCalledOnce().Object("y") }).To(PanicWithMessageTo(HavePrefix("nested: ")))
+		})
+	})
+
+	Describe("Reset", func() {
+		It("removes stubbings and invocations", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+			display.Show("Hello")
+
+			Reset(display)
+
+			Expect(display.SomeValue()).To(Equal(""))
+			display.VerifyWasCalled(Never()).Show("Hello")
+		})
+
+		It("is available as method on generated mocks", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+
+			display.Reset()
+
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("does not affect other mocks", func() {
+			otherDisplay := NewMockDisplay()
+			When(otherDisplay.SomeValue()).ThenReturn("Hello")
+
+			Reset(display)
+
+			Expect(otherDisplay.SomeValue()).To(Equal("Hello"))
+		})
+	})
+
+	Describe("ClearInvocations", func() {
+		It("removes invocations, but keeps stubbings", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+			display.Show("Hello")
+
+			ClearInvocations(display)
+
+			display.VerifyWasCalled(Never()).Show("Hello")
+			Expect(display.SomeValue()).To(Equal("Hello"))
+		})
+
+		It("keeps invocations in order relative to ones before clearing", func() {
+			otherDisplay := NewMockDisplay()
+			otherDisplay.Show("first")
+			inOrderContext := new(InOrderContext)
+			otherDisplay.VerifyWasCalledInOrder(Once(), inOrderContext).Show("first")
+
+			ClearInvocations(display)
+			display.Show("second")
+
+			display.VerifyWasCalledInOrder(Once(), inOrderContext).Show("second")
+		})
+	})
+
+	Describe("VerifyNoMoreInteractions", func() {
+		It("succeeds when there were no interactions", func() {
+			VerifyNoMoreInteractions(display)
+		})
+
+		It("succeeds when all interactions have been verified", func() {
+			display.Show("Hello")
+			display.Flash("Hello", 123)
+
+			display.VerifyWasCalledOnce().Show("Hello")
+			display.VerifyWasCalledOnce().Flash(AnyString(), AnyInt())
+
+			VerifyNoMoreInteractions(display)
+		})
+
+		It("fails listing the unverified interactions", func() {
+			display.Show("Hello")
+			display.Show("Again")
+			display.Flash("Hello", 123)
+
+			display.VerifyWasCalledOnce().Show("Hello")
+
+			Expect(func() { VerifyNoMoreInteractions(display) }).To(PanicWith(
+				"Expected no more interactions with this mock, but found unverified interactions:\n" +
+					"\tFlash(\"Hello\", 123)\n" +
+					"\tShow(\"Again\")\n",
+			))
+		})
+
+		It("does not treat invocations from failed verifications as verified", func() {
+			display.Show("Hello")
+			display.Show("Hello")
+
+			Expect(func() { display.VerifyWasCalledOnce().Show("Hello") }).To(Panic())
+
+			Expect(func() { VerifyNoMoreInteractions(display) }).To(PanicWithMessageTo(HavePrefix(
+				"Expected no more interactions with this mock, but found unverified interactions:",
+			)))
+		})
+
+		It("checks all given mocks", func() {
+			otherDisplay := NewMockDisplay()
+			display.Show("Hello")
+			otherDisplay.Show("Hello")
+
+			display.VerifyWasCalledOnce().Show("Hello")
+
+			Expect(func() { VerifyNoMoreInteractions(display, otherDisplay) }).To(PanicWithMessageTo(ContainSubstring(
+				"\tShow(\"Hello\")\n",
+			)))
+		})
+	})
+
+	Describe("VerifyZeroInteractions", func() {
+		It("succeeds when there were no interactions", func() {
+			VerifyZeroInteractions(display)
+		})
+
+		It("fails even when interactions have been verified", func() {
+			display.Show("Hello")
+			display.VerifyWasCalledOnce().Show("Hello")
+
+			Expect(func() { VerifyZeroInteractions(display) }).To(PanicWith(
+				"Expected zero interactions with this mock, but found:\n" +
+					"\tShow(\"Hello\")\n",
+			))
+		})
+	})
+
+	Describe("Strict mocks", func() {
+		var strictDisplay *MockDisplay
+
+		BeforeEach(func() {
+			strictDisplay = NewMockDisplay(WithStrictness(Strict))
+		})
+
+		It("fails on unstubbed invocation of a method without stubbings", func() {
+			Expect(func() { strictDisplay.MultipleParamsAndReturnValue("Hello", 333) }).To(PanicWith(
+				"Unstubbed invocation of MultipleParamsAndReturnValue(\"Hello\", 333) on strict mock.\n\n" +
+					"\tThere are no stubbings for MultipleParamsAndReturnValue",
+			))
+		})
+
+		It("fails on unstubbed invocation and lists existing stubbings for the method", func() {
+			When(strictDisplay.MultipleParamsAndReturnValue(EqString("Hello"), AnyInt())).ThenReturn("Bla")
+
+			Expect(func() { strictDisplay.MultipleParamsAndReturnValue("Bye", 333) }).To(PanicWith(
+				"Unstubbed invocation of MultipleParamsAndReturnValue(\"Bye\", 333) on strict mock.\n\n" +
+					"\tStubbings for MultipleParamsAndReturnValue are:\n" +
+					"\tMultipleParamsAndReturnValue(Eq(Hello), Any(int))\n",
+			))
+		})
+
+		It("fails when stubbing with raw values", func() {
+			Expect(func() { When(strictDisplay.SomeValue()).ThenReturn("some value") }).To(PanicWithMessageTo(HavePrefix(
+				"Unstubbed invocation of SomeValue() on strict mock.",
+			)))
+		})
+
+		It("does not fail on stubbed invocations", func() {
+			When(strictDisplay.MultipleParamsAndReturnValue(EqString("Hello"), AnyInt())).ThenReturn("Bla")
+			When(func() { strictDisplay.SomeValue() }).ThenReturn("some value")
+			strictDisplay.Stub().MultipleParamsAndReturnValue("Bye", 333).ThenReturn("typed")
+
+			Expect(strictDisplay.MultipleParamsAndReturnValue("Hello", 333)).To(Equal("Bla"))
+			Expect(strictDisplay.SomeValue()).To(Equal("some value"))
+			Expect(strictDisplay.MultipleParamsAndReturnValue("Bye", 333)).To(Equal("typed"))
+		})
+
+		It("calls the mock's fail handler independent of the order of options", func() {
+			var failures []string
+			strictDisplay = NewMockDisplay(WithStrictness(Strict), WithFailHandler(func(message string, callerSkip ...int) {
+				failures = append(failures, message)
+			}))
+
+			Expect(strictDisplay.SomeValue()).To(Equal(""))
+			Expect(failures).To(ConsistOf(HavePrefix("Unstubbed invocation of SomeValue() on strict mock.")))
+		})
+
+		It("can be verified like lenient mocks", func() {
+			When(strictDisplay.MultipleParamsAndReturnValue(EqString("Hello"), AnyInt())).ThenReturn("Bla")
+			strictDisplay.MultipleParamsAndReturnValue("Hello", 333)
+
+			strictDisplay.VerifyWasCalledOnce().MultipleParamsAndReturnValue("Hello", 333)
+		})
+	})
+
+	Describe("Detecting unused stubbings", func() {
+		It("succeeds when all stubbings have been used", func() {
+			When(display.MultipleParamsAndReturnValue(EqString("Hello"), AnyInt())).ThenReturn("Bla")
+			display.MultipleParamsAndReturnValue("Hello", 333)
+
+			VerifyAllStubbingsUsed(display)
+		})
+
+		It("fails listing unused stubbings with their locations", func() {
+			When(display.MultipleParamsAndReturnValue(EqString("Hello"), AnyInt())).ThenReturn("Bla")
+			When(display.SomeValue()).ThenReturn("some value")
+			display.SomeValue()
+
+			Expect(func() { VerifyAllStubbingsUsed(display) }).To(PanicWithMessageTo(SatisfyAll(
+				HavePrefix("Found unused stubbings:\n\tMultipleParamsAndReturnValue(Eq(Hello), Any(int)) stubbed at "),
+				MatchRegexp(`dsl_test.go:\d+\n$`),
+				gomega.Not(ContainSubstring("SomeValue")),
+			)))
+		})
+
+		It("reports stubbings that have only been hit by the invocation within a later When", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("any")
+			When(display.MultipleParamsAndReturnValue("Hello", 333)).ThenReturn("Bla")
+			display.MultipleParamsAndReturnValue("Hello", 333)
+
+			Expect(func() { VerifyAllStubbingsUsed(display) }).To(PanicWithMessageTo(SatisfyAll(
+				HavePrefix("Found unused stubbings:\n\tMultipleParamsAndReturnValue(Any(string), Any(int)) stubbed at "),
+				gomega.Not(ContainSubstring("Eq(Hello)")),
+			)))
+		})
+
+		It("does not report stubbings that have been replaced", func() {
+			When(display.SomeValue()).ThenReturn("first")
+			When(display.SomeValue()).ThenReturn("second")
+			display.SomeValue()
+
+			VerifyAllStubbingsUsed(display)
+		})
+
+		It("reports unused stubbings at the end of the test when opted in", func() {
+			t := &fakeT{}
+			var failures []string
+			display := NewMockDisplay(WithUnusedStubbingsCheck(t), WithFailHandler(func(message string, callerSkip ...int) {
+				failures = append(failures, message)
+			}))
+			When(display.SomeValue()).ThenReturn("some value")
+
+			t.runCleanups()
+
+			Expect(failures).To(ConsistOf(HavePrefix("Found unused stubbings:\n\tSomeValue() stubbed at ")))
+		})
+	})
+
+	Describe("Binding mocks to a test with WithT", func() {
+		var (
+			t       *fakeT
+			display *MockDisplay
+		)
+
+		BeforeEach(func() {
+			t = &fakeT{}
+			display = NewMockDisplay(WithT(t))
+		})
+
+		It("runs deferred verifications when the test ends", func() {
+			DeferVerification(display, func() { display.VerifyWasCalledOnce().Show("Hello") })
+
+			Expect(t.errors).To(BeEmpty())
+			t.runCleanups()
+
+			Expect(t.errors).To(ConsistOf(ContainSubstring("Mock invocation count for Show(\"Hello\") does not match expectation.")))
+		})
+
+		It("succeeds deferred verifications when the mock is invoked later in the test", func() {
+			DeferVerification(display, func() { display.VerifyWasCalledOnce().Show("Hello") })
+			display.Show("Hello")
+
+			t.runCleanups()
+
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("does not report unused stubbings unless requested", func() {
+			When(display.SomeValue()).ThenReturn("some value")
+
+			t.runCleanups()
+
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("does not report unused stubbings of strict mocks unless requested", func() {
+			display = NewMockDisplay(WithT(t), WithStrictness(Strict))
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("Bla")
+
+			t.runCleanups()
+
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("reports unused stubbings of strict mocks with WithUnusedStubbingsCheck", func() {
+			display = NewMockDisplay(WithT(t), WithStrictness(Strict), WithUnusedStubbingsCheck(t))
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("Bla")
+
+			t.runCleanups()
+
+			Expect(t.errors).To(ConsistOf(ContainSubstring("Found unused stubbings:")))
+		})
+
+		It("resets the mock when the test ends", func() {
+			When(display.SomeValue()).ThenReturn("some value")
+			display.Show("Hello")
+
+			t.runCleanups()
+
+			Expect(display.SomeValue()).To(Equal(""))
+			display.VerifyWasCalled(Never()).Show("Hello")
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("does not allow deferred verifications on mocks not bound to a test", func() {
+			Expect(func() { DeferVerification(NewMockDisplay(), func() {}) }).To(PanicWith(
+				"DeferVerification requires a mock that is bound to a test. Please create the mock using WithT.",
+			))
+		})
+
+		It("rejects deferred verifications and unused-stubbing checks with GinkgoT() of Ginkgo v1, which never runs cleanups", func() {
+			display := NewMockDisplay(WithT(ginkgo.GinkgoT()))
+
+			Expect(func() { DeferVerification(display, func() {}) }).To(PanicWith(
+				"DeferVerification requires a test that runs cleanup functions, but GinkgoT() of Ginkgo v1 ignores them. " +
+					"Please verify the mock at the end of the test instead.",
+			))
+			Expect(func() { WithUnusedStubbingsCheck(ginkgo.GinkgoT()) }).To(PanicWith(
+				"WithUnusedStubbingsCheck requires a test that runs cleanup functions, but GinkgoT() of Ginkgo v1 ignores them. " +
+					"Please call VerifyAllStubbingsUsed at the end of the test instead.",
+			))
+		})
+	})
+
+	Describe("RegisterMockTestingT", func() {
+		It("restores the previous global fail handler when the test ends", func() {
+			t := &fakeT{}
+			RegisterMockTestingT(t)
+
+			display.VerifyWasCalledOnce().Show("Hello")
+			Expect(t.errors).To(HaveLen(1))
+
+			t.runCleanups()
+
+			Expect(func() { display.VerifyWasCalledOnce().Show("Hello") }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Show(\"Hello\") does not match expectation.",
+			)))
+		})
+	})
+
+	Describe("Garbage collection", func() {
+		It("does not keep generated mocks and their recorded params alive", func() {
+			collected := make(chan bool, 1)
+			func() {
+				display := NewMockDisplay()
+				param := &struct{ payload []byte }{make([]byte, 1024)}
+				runtime.SetFinalizer(param, func(interface{}) { collected <- true })
+				display.InterfaceParam(param)
+				display.VerifyWasCalledOnce().InterfaceParam(param)
+				// replaces the last invocation kept for the next When
+				When(display.SomeValue()).ThenReturn("Hello")
+			}()
+
+			Eventually(func() bool {
+				runtime.GC()
+				select {
+				case <-collected:
+					return true
+				default:
+					return false
+				}
+			}).Should(BeTrue())
+		})
+	})
+
+	Describe("NewContext", func() {
+		It("binds all mocks created with the context to the test", func() {
+			t := &fakeT{}
+			ctx := NewContext(t)
+			display1, display2 := NewMockDisplay(ctx), NewMockDisplay(ctx)
+			When(display1.SomeValue()).ThenReturn("Hello")
+
+			display2.VerifyWasCalledOnce().Show("Hello")
+			Expect(t.errors).To(HaveLen(1))
+
+			t.runCleanups()
+
+			Expect(display1.SomeValue()).To(Equal(""))
+		})
+
+		It("discards an unfinished stubbing when the test ends", func() {
+			t := &fakeT{}
+			display := NewMockDisplay(NewContext(t))
+			display.MultipleParamsAndReturnValue(AnyString(), AnyInt())
+
+			t.runCleanups()
+
+			Expect(func() { When(display.SomeValue()).ThenReturn("Hello") }).NotTo(Panic())
+			Expect(display.SomeValue()).To(Equal("Hello"))
+		})
+	})
+
+	Context("channels", func() {
+
+		Context("using send-/receive-only channels in return types", func() {
+			It("allows to return non-direction channels from callbacks", func() {
+				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
+					return []ReturnValue{make(chan string), make(chan error)}
+				})
+				display.ChanReturnValues()
+			})
+
+			It("allows to return directed channels from callbacks", func() {
+				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
+					return []ReturnValue{make(<-chan string), make(chan<- error)}
+				})
+				display.ChanReturnValues()
+			})
+
+			It("does not allow to return directed channels from callbacks with wrong direction", func() {
+				When(display.ChanReturnValues()).Then(func([]pegomock.Param) pegomock.ReturnValues {
+					return []ReturnValue{make(chan<- string), make(chan<- error)}
+				})
+
+				Expect(func() { display.ChanReturnValues() }).To(PanicWithMessageTo(MatchError(
+					"interface conversion: pegomock.ReturnValue is chan<- string, not <-chan string",
+				)))
+
+			})
+		})
+
+		Context("using send-/receive-only channels", func() {
+			It("generates the mock method with correct channel directions", func() {
+				var stringReadChan <-chan string
+				var errorWriteChan chan<- error
+				display.ChanParams(stringReadChan, errorWriteChan)
+			})
+		})
+	})
+})
+
+// realDisplay implements the parts of test_interface.Display used as delegate in tests.
+type realDisplay struct {
+	shown []string
+}
+
+func (d *realDisplay) Show(s string)             { d.shown = append(d.shown, s) }
+func (d *realDisplay) VariadicParam(v ...string) { d.shown = append(d.shown, v...) }
+func (d *realDisplay) SomeValue() string         { return "real value" }
+
+func (d *realDisplay) MultipleParamsAndReturnValue(s string, i int) string {
+	return strings.TrimSpace(strings.Repeat(s+" ", i))
+}
+
+type fakeT struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (t *fakeT) Cleanup(cleanup func()) { t.cleanups = append(t.cleanups, cleanup) }
+func (t *fakeT) Helper()                {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) runCleanups() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func flattenStringSliceOfSlices(sliceOfSlices [][]string) (result []string) {
+	for _, slice := range sliceOfSlices {
+		result = append(result, slice...)
+	}
+	return
+}
+
+type expectation struct {
+	method   string
+	expected string
+	actual   string
+}
+
+func (e expectation) string() string {
+	return fmt.Sprintf("Mock invocation count for %v does not match expectation.\n\n\tExpected: %v; but got: %v",
+		e.method, e.expected, e.actual)
+}
+
+// versionForEquality is only used to register a global equality func, which must not affect other tests.
+type versionForEquality struct {
+	major, minor int
+}
+
+type lengthForEquality struct {
+	value int
+	unit  string
 }
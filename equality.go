@@ -0,0 +1,187 @@
+package pegomock
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// equality configures how Eq matching compares values. Without any configuration, it uses reflect.DeepEqual.
+// Otherwise, it uses cmp.Equal with the options combined from its own and the global configuration, as long as
+// any of them applies to the compared values. These options are cached until either configuration changes.
+type equality struct {
+	equalFuncs map[reflect.Type]interface{}
+	cmpOptions []cmp.Option
+
+	cachedOptions          cmp.Options
+	cachedGlobalGeneration uint64 // 0 if cachedOptions are invalid
+	sync.Mutex
+}
+
+var (
+	globalEqualityMutex sync.RWMutex
+	globalEquality      equality
+	// globalEqualityGeneration changes with every change of globalEquality, to invalidate all cached options.
+	globalEqualityGeneration uint64 = 1
+	// noEquality is used by matchers that are not bound to a mock's equality.
+	noEquality equality
+)
+
+// RegisterEquality makes Eq matching of all mocks compare values of type T with equal, which must be a func(T, T) bool.
+// This is useful for types that reflect.DeepEqual does not compare correctly, like protobuf messages.
+// Mocks created with WithEquality for the same type use their own func instead.
+//
+// Values that contain a T are then compared with cmp.Equal instead of reflect.DeepEqual. Like reflect.DeepEqual,
+// it compares unexported fields, but unlike it, it uses Equal methods like time.Time's. Values of other types are
+// still compared with reflect.DeepEqual. Use ResetEquality to undo all global registrations, e.g. after each test.
+func RegisterEquality(equal interface{}) {
+	equalType := verifyEqualFunc("RegisterEquality", equal)
+	globalEqualityMutex.Lock()
+	defer globalEqualityMutex.Unlock()
+	globalEquality.addEqualFunc(equalType, equal)
+	globalEqualityGeneration++
+}
+
+// RegisterCmpOptions makes Eq matching of all mocks compare values with cmp.Equal and options, if any of the options
+// applies to the compared values.
+func RegisterCmpOptions(options ...cmp.Option) {
+	globalEqualityMutex.Lock()
+	defer globalEqualityMutex.Unlock()
+	globalEquality.addCmpOptions(options)
+	globalEqualityGeneration++
+}
+
+// ResetEquality removes all equality funcs and cmp options registered with RegisterEquality and RegisterCmpOptions.
+func ResetEquality() {
+	globalEqualityMutex.Lock()
+	defer globalEqualityMutex.Unlock()
+	globalEquality.equalFuncs = nil
+	globalEquality.cmpOptions = nil
+	globalEqualityGeneration++
+}
+
+// WithEquality is like RegisterEquality, but only for the mock.
+func WithEquality(equal interface{}) Option {
+	equalType := verifyEqualFunc("WithEquality", equal)
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).equality.addEqualFunc(equalType, equal) })
+}
+
+// WithCmpOptions is like RegisterCmpOptions, but only for the mock.
+func WithCmpOptions(options ...cmp.Option) Option {
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).equality.addCmpOptions(options) })
+}
+
+func verifyEqualFunc(functionName string, equal interface{}) reflect.Type {
+	equalType := reflect.TypeOf(equal)
+	verify.Argument(equalType != nil && equalType.Kind() == reflect.Func && !reflect.ValueOf(equal).IsNil() &&
+		equalType.NumIn() == 2 && equalType.In(0) == equalType.In(1) && !equalType.IsVariadic() &&
+		equalType.NumOut() == 1 && equalType.Out(0).Kind() == reflect.Bool,
+		"%v() requires a func(T, T) bool, but got %T.", functionName, equal)
+	return equalType.In(0)
+}
+
+func (equality *equality) addEqualFunc(equalType reflect.Type, equal interface{}) {
+	equality.Lock()
+	defer equality.Unlock()
+	if equality.equalFuncs == nil {
+		equality.equalFuncs = make(map[reflect.Type]interface{})
+	}
+	equality.equalFuncs[equalType] = equal
+	equality.cachedGlobalGeneration = 0
+}
+
+func (equality *equality) addCmpOptions(options []cmp.Option) {
+	equality.Lock()
+	defer equality.Unlock()
+	equality.cmpOptions = append(equality.cmpOptions, options...)
+	equality.cachedGlobalGeneration = 0
+}
+
+// equal compares a and b with the equality of a mock and the global one. equality may be nil.
+func (equality *equality) equal(a, b Param) bool {
+	options := equality.options()
+	if len(options) == 0 {
+		return reflect.DeepEqual(a, b)
+	}
+	var reporter optionReporter
+	if equal := cmp.Equal(a, b, options, cmp.Reporter(&reporter)); reporter.optionApplied {
+		return equal
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// optionReporter finds out whether any comparer, ignore option or transformer has been applied by cmp.Equal.
+// Equal methods and the exporter of unexported fields don't count, because they are applied by default.
+type optionReporter struct {
+	optionApplied bool
+}
+
+func (reporter *optionReporter) PushStep(step cmp.PathStep) {
+	if _, isTransform := step.(cmp.Transform); isTransform {
+		reporter.optionApplied = true
+	}
+}
+
+func (reporter *optionReporter) Report(result cmp.Result) {
+	if result.ByFunc() || result.ByIgnore() {
+		reporter.optionApplied = true
+	}
+}
+
+func (reporter *optionReporter) PopStep() {}
+
+func (equality *equality) options() cmp.Options {
+	if equality == nil {
+		equality = &noEquality
+	}
+	globalEqualityMutex.RLock()
+	defer globalEqualityMutex.RUnlock()
+	equality.Lock()
+	defer equality.Unlock()
+	if equality.cachedGlobalGeneration != globalEqualityGeneration {
+		equality.cachedOptions = equality.combinedOptions()
+		equality.cachedGlobalGeneration = globalEqualityGeneration
+	}
+	return equality.cachedOptions
+}
+
+// combinedOptions must be called with globalEqualityMutex and the equality's lock held.
+func (equality *equality) combinedOptions() cmp.Options {
+	equalFuncs := make(map[reflect.Type]interface{}, len(globalEquality.equalFuncs))
+	for equalType, equal := range globalEquality.equalFuncs {
+		equalFuncs[equalType] = equal
+	}
+	options := append(cmp.Options{}, globalEquality.cmpOptions...)
+	for equalType, equal := range equality.equalFuncs {
+		equalFuncs[equalType] = equal
+	}
+	options = append(options, equality.cmpOptions...)
+	if len(equalFuncs) == 0 && len(options) == 0 {
+		return nil
+	}
+	for _, equal := range equalFuncs {
+		options = append(options, cmp.Comparer(equal))
+	}
+	// Like reflect.DeepEqual, compare unexported fields instead of panicking.
+	return append(options, cmp.Exporter(func(reflect.Type) bool { return true }))
+}
+
+// equalityUser is implemented by matchers that compare values for equality.
+type equalityUser interface {
+	useEquality(equality *equality)
+}
+
+// bindEquality makes matchers that compare values for equality use the equality of genericMock.
+func (genericMock *GenericMock) bindEquality(matchers []Matcher) {
+	useEquality(matchers, &genericMock.equality)
+}
+
+func useEquality(matchers []Matcher, equality *equality) {
+	for _, matcher := range matchers {
+		if user, isEqualityUser := matcher.(equalityUser); isEqualityUser {
+			user.useEquality(equality)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package mockgen_test
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -29,4 +30,10 @@ var _ = It("Generate mocks", func() {
 		[]string{"github.com/petergtz/pegomock/test_interface", "Display"},
 		"../../mock_display_test.go", "MockDisplay", "pegomock_test",
 		"", false, os.Stdout, false, true, "")
+	for _, name := range []string{"Storage", "Bucket", "Object", "Broker"} {
+		filehandling.GenerateMockFile(
+			[]string{"github.com/petergtz/pegomock/test_interface", name},
+			"../../mock_"+strings.ToLower(name)+"_test.go", "Mock"+name, "pegomock_test",
+			"", false, os.Stdout, false, true, "")
+	}
 })
@@ -15,6 +15,7 @@
 package mockgen_test
 
 import (
+	"flag"
 	"os"
 	"testing"
 
@@ -29,4 +30,19 @@ var _ = It("Generate mocks", func() {
 		[]string{"../../test_interface/display.go"},
 		"../../mock_display_test.go", "MockDisplay", "pegomock_test",
 		"", false, os.Stdout, false, true, "")
+	filehandling.GenerateMockFile(
+		[]string{"../../test_interface/broker.go"},
+		"../../mock_broker_test.go", "MockBroker", "pegomock_test",
+		"", false, os.Stdout, false, true, "")
+
+	// The fluent interfaces refer to each other without package qualifier, hence the dot import.
+	// Matchers are not generated for them, since source mode cannot qualify such types in the matchers package.
+	if err := flag.Set("imports", ".=github.com/petergtz/pegomock/test_interface"); err != nil {
+		panic(err)
+	}
+	defer flag.Set("imports", "")
+	filehandling.GenerateMockFile(
+		[]string{"../../test_interface/fluent.go"},
+		"../../mock_fluent_test.go", "", "pegomock_test",
+		"", false, os.Stdout, false, false, "")
 })
@@ -4,56 +4,192 @@ import (
 	"github.com/petergtz/pegomock"
 )
 
+// pegomock's And and Or are left out, since they would collide with Gomega's when dot-importing both.
+// Use AllOf and AnyOf instead. For the same reason, pegomock's Gomega is called AdaptGomegaMatcher here.
 var (
-	EqBool             = pegomock.EqBool
-	AnyBool            = pegomock.AnyBool
-	AnyBoolSlice       = pegomock.AnyBoolSlice
-	EqInt              = pegomock.EqInt
-	AnyInt             = pegomock.AnyInt
-	AnyIntSlice        = pegomock.AnyIntSlice
-	EqInt8             = pegomock.EqInt8
-	AnyInt8            = pegomock.AnyInt8
-	AnyInt8Slice       = pegomock.AnyInt8Slice
-	EqInt16            = pegomock.EqInt16
-	AnyInt16           = pegomock.AnyInt16
-	AnyInt16Slice      = pegomock.AnyInt16Slice
-	EqInt32            = pegomock.EqInt32
-	AnyInt32           = pegomock.AnyInt32
-	AnyInt32Slice      = pegomock.AnyInt32Slice
-	EqInt64            = pegomock.EqInt64
-	AnyInt64           = pegomock.AnyInt64
-	AnyInt64Slice      = pegomock.AnyInt64Slice
-	EqUint             = pegomock.EqUint
-	AnyUint            = pegomock.AnyUint
-	AnyUintSlice       = pegomock.AnyUintSlice
-	EqUint8            = pegomock.EqUint8
-	AnyUint8           = pegomock.AnyUint8
-	AnyUint8Slice      = pegomock.AnyUint8Slice
-	EqUint16           = pegomock.EqUint16
-	AnyUint16          = pegomock.AnyUint16
-	AnyUint16Slice     = pegomock.AnyUint16Slice
-	EqUint32           = pegomock.EqUint32
-	AnyUint32          = pegomock.AnyUint32
-	AnyUint32Slice     = pegomock.AnyUint32Slice
-	EqUint64           = pegomock.EqUint64
-	AnyUint64          = pegomock.AnyUint64
-	AnyUint64Slice     = pegomock.AnyUint64Slice
-	EqUintptr          = pegomock.EqUintptr
-	AnyUintptr         = pegomock.AnyUintptr
-	AnyUintptrSlice    = pegomock.AnyUintptrSlice
-	EqFloat32          = pegomock.EqFloat32
-	AnyFloat32         = pegomock.AnyFloat32
-	AnyFloat32Slice    = pegomock.AnyFloat32Slice
-	EqFloat64          = pegomock.EqFloat64
-	AnyFloat64         = pegomock.AnyFloat64
-	AnyFloat64Slice    = pegomock.AnyFloat64Slice
-	EqComplex64        = pegomock.EqComplex64
-	AnyComplex64       = pegomock.AnyComplex64
-	AnyComplex64Slice  = pegomock.AnyComplex64Slice
-	EqComplex128       = pegomock.EqComplex128
-	AnyComplex128      = pegomock.AnyComplex128
-	AnyComplex128Slice = pegomock.AnyComplex128Slice
-	EqString           = pegomock.EqString
-	AnyString          = pegomock.AnyString
-	AnyStringSlice     = pegomock.AnyStringSlice
+	AdaptGomegaMatcher        = pegomock.Gomega
+	NewPredicateMatcher       = pegomock.NewPredicateMatcher
+	ArgThat                   = pegomock.ArgThat
+	Eq                        = pegomock.Eq
+	NotEq                     = pegomock.NotEq
+	That                      = pegomock.That
+	NoneOf                    = pegomock.NoneOf
+	AllOf                     = pegomock.AllOf
+	AnyOf                     = pegomock.AnyOf
+	EqBool                    = pegomock.EqBool
+	AnyBool                   = pegomock.AnyBool
+	AnyBoolSlice              = pegomock.AnyBoolSlice
+	BoolThat                  = pegomock.BoolThat
+	BoolThatMatches           = pegomock.BoolThatMatches
+	NotEqBool                 = pegomock.NotEqBool
+	BoolMatching              = pegomock.BoolMatching
+	EqInt                     = pegomock.EqInt
+	AnyInt                    = pegomock.AnyInt
+	AnyIntSlice               = pegomock.AnyIntSlice
+	IntThat                   = pegomock.IntThat
+	IntThatMatches            = pegomock.IntThatMatches
+	NotEqInt                  = pegomock.NotEqInt
+	IntMatching               = pegomock.IntMatching
+	IntGreaterThan            = pegomock.IntGreaterThan
+	IntLessThan               = pegomock.IntLessThan
+	IntBetween                = pegomock.IntBetween
+	EqInt8                    = pegomock.EqInt8
+	AnyInt8                   = pegomock.AnyInt8
+	AnyInt8Slice              = pegomock.AnyInt8Slice
+	Int8That                  = pegomock.Int8That
+	Int8ThatMatches           = pegomock.Int8ThatMatches
+	NotEqInt8                 = pegomock.NotEqInt8
+	Int8Matching              = pegomock.Int8Matching
+	Int8GreaterThan           = pegomock.Int8GreaterThan
+	Int8LessThan              = pegomock.Int8LessThan
+	Int8Between               = pegomock.Int8Between
+	EqInt16                   = pegomock.EqInt16
+	AnyInt16                  = pegomock.AnyInt16
+	AnyInt16Slice             = pegomock.AnyInt16Slice
+	Int16That                 = pegomock.Int16That
+	Int16ThatMatches          = pegomock.Int16ThatMatches
+	NotEqInt16                = pegomock.NotEqInt16
+	Int16Matching             = pegomock.Int16Matching
+	Int16GreaterThan          = pegomock.Int16GreaterThan
+	Int16LessThan             = pegomock.Int16LessThan
+	Int16Between              = pegomock.Int16Between
+	EqInt32                   = pegomock.EqInt32
+	AnyInt32                  = pegomock.AnyInt32
+	AnyInt32Slice             = pegomock.AnyInt32Slice
+	Int32That                 = pegomock.Int32That
+	Int32ThatMatches          = pegomock.Int32ThatMatches
+	NotEqInt32                = pegomock.NotEqInt32
+	Int32Matching             = pegomock.Int32Matching
+	Int32GreaterThan          = pegomock.Int32GreaterThan
+	Int32LessThan             = pegomock.Int32LessThan
+	Int32Between              = pegomock.Int32Between
+	EqInt64                   = pegomock.EqInt64
+	AnyInt64                  = pegomock.AnyInt64
+	AnyInt64Slice             = pegomock.AnyInt64Slice
+	Int64That                 = pegomock.Int64That
+	Int64ThatMatches          = pegomock.Int64ThatMatches
+	NotEqInt64                = pegomock.NotEqInt64
+	Int64Matching             = pegomock.Int64Matching
+	Int64GreaterThan          = pegomock.Int64GreaterThan
+	Int64LessThan             = pegomock.Int64LessThan
+	Int64Between              = pegomock.Int64Between
+	EqUint                    = pegomock.EqUint
+	AnyUint                   = pegomock.AnyUint
+	AnyUintSlice              = pegomock.AnyUintSlice
+	UintThat                  = pegomock.UintThat
+	UintThatMatches           = pegomock.UintThatMatches
+	NotEqUint                 = pegomock.NotEqUint
+	UintMatching              = pegomock.UintMatching
+	UintGreaterThan           = pegomock.UintGreaterThan
+	UintLessThan              = pegomock.UintLessThan
+	UintBetween               = pegomock.UintBetween
+	EqUint8                   = pegomock.EqUint8
+	AnyUint8                  = pegomock.AnyUint8
+	AnyUint8Slice             = pegomock.AnyUint8Slice
+	Uint8That                 = pegomock.Uint8That
+	Uint8ThatMatches          = pegomock.Uint8ThatMatches
+	NotEqUint8                = pegomock.NotEqUint8
+	Uint8Matching             = pegomock.Uint8Matching
+	Uint8GreaterThan          = pegomock.Uint8GreaterThan
+	Uint8LessThan             = pegomock.Uint8LessThan
+	Uint8Between              = pegomock.Uint8Between
+	EqUint16                  = pegomock.EqUint16
+	AnyUint16                 = pegomock.AnyUint16
+	AnyUint16Slice            = pegomock.AnyUint16Slice
+	Uint16That                = pegomock.Uint16That
+	Uint16ThatMatches         = pegomock.Uint16ThatMatches
+	NotEqUint16               = pegomock.NotEqUint16
+	Uint16Matching            = pegomock.Uint16Matching
+	Uint16GreaterThan         = pegomock.Uint16GreaterThan
+	Uint16LessThan            = pegomock.Uint16LessThan
+	Uint16Between             = pegomock.Uint16Between
+	EqUint32                  = pegomock.EqUint32
+	AnyUint32                 = pegomock.AnyUint32
+	AnyUint32Slice            = pegomock.AnyUint32Slice
+	Uint32That                = pegomock.Uint32That
+	Uint32ThatMatches         = pegomock.Uint32ThatMatches
+	NotEqUint32               = pegomock.NotEqUint32
+	Uint32Matching            = pegomock.Uint32Matching
+	Uint32GreaterThan         = pegomock.Uint32GreaterThan
+	Uint32LessThan            = pegomock.Uint32LessThan
+	Uint32Between             = pegomock.Uint32Between
+	EqUint64                  = pegomock.EqUint64
+	AnyUint64                 = pegomock.AnyUint64
+	AnyUint64Slice            = pegomock.AnyUint64Slice
+	Uint64That                = pegomock.Uint64That
+	Uint64ThatMatches         = pegomock.Uint64ThatMatches
+	NotEqUint64               = pegomock.NotEqUint64
+	Uint64Matching            = pegomock.Uint64Matching
+	Uint64GreaterThan         = pegomock.Uint64GreaterThan
+	Uint64LessThan            = pegomock.Uint64LessThan
+	Uint64Between             = pegomock.Uint64Between
+	EqUintptr                 = pegomock.EqUintptr
+	AnyUintptr                = pegomock.AnyUintptr
+	AnyUintptrSlice           = pegomock.AnyUintptrSlice
+	UintptrThat               = pegomock.UintptrThat
+	UintptrThatMatches        = pegomock.UintptrThatMatches
+	NotEqUintptr              = pegomock.NotEqUintptr
+	UintptrMatching           = pegomock.UintptrMatching
+	UintptrGreaterThan        = pegomock.UintptrGreaterThan
+	UintptrLessThan           = pegomock.UintptrLessThan
+	UintptrBetween            = pegomock.UintptrBetween
+	EqFloat32                 = pegomock.EqFloat32
+	AnyFloat32                = pegomock.AnyFloat32
+	AnyFloat32Slice           = pegomock.AnyFloat32Slice
+	Float32That               = pegomock.Float32That
+	Float32ThatMatches        = pegomock.Float32ThatMatches
+	NotEqFloat32              = pegomock.NotEqFloat32
+	Float32Matching           = pegomock.Float32Matching
+	Float32GreaterThan        = pegomock.Float32GreaterThan
+	Float32LessThan           = pegomock.Float32LessThan
+	Float32Between            = pegomock.Float32Between
+	Float32CloseTo            = pegomock.Float32CloseTo
+	EqFloat64                 = pegomock.EqFloat64
+	AnyFloat64                = pegomock.AnyFloat64
+	AnyFloat64Slice           = pegomock.AnyFloat64Slice
+	Float64That               = pegomock.Float64That
+	Float64ThatMatches        = pegomock.Float64ThatMatches
+	NotEqFloat64              = pegomock.NotEqFloat64
+	Float64Matching           = pegomock.Float64Matching
+	Float64GreaterThan        = pegomock.Float64GreaterThan
+	Float64LessThan           = pegomock.Float64LessThan
+	Float64Between            = pegomock.Float64Between
+	Float64CloseTo            = pegomock.Float64CloseTo
+	EqComplex64               = pegomock.EqComplex64
+	AnyComplex64              = pegomock.AnyComplex64
+	AnyComplex64Slice         = pegomock.AnyComplex64Slice
+	Complex64That             = pegomock.Complex64That
+	Complex64ThatMatches      = pegomock.Complex64ThatMatches
+	NotEqComplex64            = pegomock.NotEqComplex64
+	Complex64Matching         = pegomock.Complex64Matching
+	EqComplex128              = pegomock.EqComplex128
+	AnyComplex128             = pegomock.AnyComplex128
+	AnyComplex128Slice        = pegomock.AnyComplex128Slice
+	Complex128That            = pegomock.Complex128That
+	Complex128ThatMatches     = pegomock.Complex128ThatMatches
+	NotEqComplex128           = pegomock.NotEqComplex128
+	Complex128Matching        = pegomock.Complex128Matching
+	EqString                  = pegomock.EqString
+	AnyString                 = pegomock.AnyString
+	AnyStringSlice            = pegomock.AnyStringSlice
+	StringThat                = pegomock.StringThat
+	StringThatMatches         = pegomock.StringThatMatches
+	NotEqString               = pegomock.NotEqString
+	StringMatching            = pegomock.StringMatching
+	StringContaining          = pegomock.StringContaining
+	StringHasPrefix           = pegomock.StringHasPrefix
+	StringHasSuffix           = pegomock.StringHasSuffix
+	StringMatchingRegex       = pegomock.StringMatchingRegex
+	StringEqualFold           = pegomock.StringEqualFold
+	BytesContaining           = pegomock.BytesContaining
+	BytesHasPrefix            = pegomock.BytesHasPrefix
+	BytesHasSuffix            = pegomock.BytesHasSuffix
+	BytesMatchingRegex        = pegomock.BytesMatchingRegex
+	BytesEqualFold            = pegomock.BytesEqualFold
+	SliceContaining           = pegomock.SliceContaining
+	SliceContainingInAnyOrder = pegomock.SliceContainingInAnyOrder
+	SliceOfLen                = pegomock.SliceOfLen
+	MapHasKey                 = pegomock.MapHasKey
+	MapHasEntry               = pegomock.MapHasEntry
+	StructWithFields          = pegomock.StructWithFields
 )
@@ -5,3 +5,4 @@ import (
 )
 
 var OptionWithT = pegomock.WithT
+var OptionNewContext = pegomock.NewContext
@@ -28,6 +28,8 @@ func GenerateDefaultMatchersFile() string {
 
 import (
 	"reflect"
+
+	"github.com/onsi/gomega/types"
 )
 
 %s
@@ -39,7 +41,17 @@ func GenerateDefaultMatchers() string {
 	for _, kind := range primitiveKinds {
 		result += GenerateEqMatcherFactory(kind) +
 			GenerateAnyMatcherFactory(kind) +
-			GenerateAnySliceMatcherFactory(kind)
+			GenerateAnySliceMatcherFactory(kind) +
+			GenerateThatMatcherFactory(kind) +
+			GenerateThatMatchesMatcherFactory(kind) +
+			GenerateNotEqMatcherFactory(kind) +
+			GenerateMatchingMatcherFactory(kind)
+		if isNumeric(kind) {
+			result += GenerateComparisonMatcherFactories(kind)
+		}
+		if kind == reflect.Float32 || kind == reflect.Float64 {
+			result += GenerateCloseToMatcherFactory(kind)
+		}
 	}
 	// hard-coding this for now as interface{} overall works slighly different than other types.
 	result += `func EqInterface(value interface{}) interface{} {
@@ -106,6 +118,77 @@ func GenerateAnySliceMatcherFactory(kind reflect.Kind) string {
 `, strings.Title(kind.String()), kind.String(), kind.String(), nullOf(kind))
 }
 
+func GenerateThatMatcherFactory(kind reflect.Kind) string {
+	return fmt.Sprintf(`func %sThat(predicate func(%s) bool) %s {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return %s
+}
+
+`, strings.Title(kind.String()), kind, kind, nullOf(kind))
+}
+
+func GenerateThatMatchesMatcherFactory(kind reflect.Kind) string {
+	return fmt.Sprintf(`func %sThatMatches(matcher types.GomegaMatcher) %s {
+	RegisterMatcher(Gomega(matcher))
+	return %s
+}
+
+`, strings.Title(kind.String()), kind, nullOf(kind))
+}
+
+func GenerateNotEqMatcherFactory(kind reflect.Kind) string {
+	return fmt.Sprintf(`func NotEq%s(value %s) %s {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return %s
+}
+
+`, strings.Title(kind.String()), kind, kind, nullOf(kind))
+}
+
+// GenerateMatchingMatcherFactory generates a factory that registers any Matcher, so that matchers combined
+// with AllOf, AnyOf and NoneOf can be used for params of the kind, e.g. StringMatching(AnyOf(Eq("a"), Eq("b"))).
+func GenerateMatchingMatcherFactory(kind reflect.Kind) string {
+	return fmt.Sprintf(`func %sMatching(matcher Matcher) %s {
+	RegisterMatcher(matcher)
+	return %s
+}
+
+`, strings.Title(kind.String()), kind, nullOf(kind))
+}
+
+func GenerateComparisonMatcherFactories(kind reflect.Kind) string {
+	title := strings.Title(kind.String())
+	return fmt.Sprintf(`func %[1]sGreaterThan(value %[2]s) %[2]s {
+	RegisterMatcher(&NumericMatcher{Name: "%[1]sGreaterThan", Values: []Param{value}})
+	return %[3]s
+}
+
+func %[1]sLessThan(value %[2]s) %[2]s {
+	RegisterMatcher(&NumericMatcher{Name: "%[1]sLessThan", Values: []Param{value}})
+	return %[3]s
+}
+
+func %[1]sBetween(min, max %[2]s) %[2]s {
+	RegisterMatcher(&NumericMatcher{Name: "%[1]sBetween", Values: []Param{min, max}})
+	return %[3]s
+}
+
+`, title, kind, nullOf(kind))
+}
+
+func GenerateCloseToMatcherFactory(kind reflect.Kind) string {
+	return fmt.Sprintf(`func %[1]sCloseTo(value, epsilon %[2]s) %[2]s {
+	RegisterMatcher(&NumericMatcher{Name: "%[1]sCloseTo", Values: []Param{value, epsilon}})
+	return %[3]s
+}
+
+`, strings.Title(kind.String()), kind, nullOf(kind))
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return reflect.Int <= kind && kind <= reflect.Float64
+}
+
 // TODO generate:
 // Eq Slice matchers
 // generate chan, func matchers
@@ -2,15 +2,40 @@ package pegomock
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"strings"
 
+	"github.com/onsi/gomega/types"
 	"github.com/petergtz/pegomock/internal/verify"
 	"sync"
 )
 
+// Eq, NotEq and That create matchers without registering them, unlike the Eq..., NotEq... and ...That factories.
+// Use them as operands of AllOf, AnyOf and NoneOf, or as field values of StructWithFields:
+//
+//	When(phoneBook.GetPhoneNumber(StringMatching(AnyOf(Eq("Tom"), Eq("Dan"))))).ThenReturn("345-123-789")
+
+// Eq matches params equal to value.
+func Eq(value Param) *EqMatcher {
+	return &EqMatcher{Value: value}
+}
+
+// NotEq matches params not equal to value.
+func NotEq(value Param) *NoneOfMatcher {
+	return NoneOf(Eq(value))
+}
+
+// That matches params that satisfy matcher, which can be a Gomega matcher or a predicate of the form func(T) bool.
+// Note that predicates never compare equal, see PredicateMatcher.
+func That(matcher interface{}) Matcher {
+	return argThatMatcher("That", matcher)
+}
+
 type EqMatcher struct {
-	Value  Param
-	actual Param
+	Value    Param
+	actual   Param
+	equality *equality
 	sync.Mutex
 }
 
@@ -19,7 +44,16 @@ func (matcher *EqMatcher) Matches(param Param) bool {
 	defer matcher.Unlock()
 
 	matcher.actual = param
-	return reflect.DeepEqual(matcher.Value, param)
+	return matcher.equality.equal(matcher.Value, param)
+}
+
+func (matcher *EqMatcher) useEquality(equality *equality) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matcher.equality == nil {
+		matcher.equality = equality
+	}
 }
 
 func (matcher *EqMatcher) FailureMessage() string {
@@ -101,3 +135,309 @@ func (matcher *AtMostIntMatcher) FailureMessage() string {
 func (matcher *AtMostIntMatcher) String() string {
 	return fmt.Sprintf("AtMost(%v)", matcher.Value)
 }
+
+// NumericMatcher matches numbers of the same type as Values that satisfy the comparison named by the suffix of Name,
+// i.e. GreaterThan, LessThan, Between or CloseTo, with Values as operands. It holds no funcs, so a later When
+// with an equal NumericMatcher replaces the stubbing.
+type NumericMatcher struct {
+	Name   string
+	Values []Param
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *NumericMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	if param == nil || reflect.TypeOf(param) != reflect.TypeOf(matcher.Values[0]) {
+		return false
+	}
+	actual := reflect.ValueOf(param)
+	switch {
+	case strings.HasSuffix(matcher.Name, "GreaterThan"):
+		less, _ := compareNumbers(reflect.ValueOf(matcher.Values[0]), actual)
+		return less
+	case strings.HasSuffix(matcher.Name, "LessThan"):
+		less, _ := compareNumbers(actual, reflect.ValueOf(matcher.Values[0]))
+		return less
+	case strings.HasSuffix(matcher.Name, "Between"):
+		aboveMin, atMin := compareNumbers(reflect.ValueOf(matcher.Values[0]), actual)
+		belowMax, atMax := compareNumbers(actual, reflect.ValueOf(matcher.Values[1]))
+		return (aboveMin || atMin) && (belowMax || atMax)
+	case strings.HasSuffix(matcher.Name, "CloseTo"):
+		return math.Abs(actual.Float()-reflect.ValueOf(matcher.Values[0]).Float()) <= reflect.ValueOf(matcher.Values[1]).Float()
+	default:
+		panic(fmt.Sprintf("Unknown numeric matcher %v", matcher.Name))
+	}
+}
+
+// compareNumbers reports whether a is less than b and whether it is equal to b. a and b must be of the same kind.
+// For NaN, both are false.
+func compareNumbers(a, b reflect.Value) (less, equal bool) {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int(), a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint(), a.Uint() == b.Uint()
+	default:
+		return a.Float() < b.Float(), a.Float() == b.Float()
+	}
+}
+
+func (matcher *NumericMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %v", matcher, matcher.actual)
+}
+
+func (matcher *NumericMatcher) String() string {
+	values := make([]string, len(matcher.Values))
+	for i, value := range matcher.Values {
+		values[i] = fmt.Sprint(value)
+	}
+	return fmt.Sprintf("%v(%v)", matcher.Name, strings.Join(values, ", "))
+}
+
+type NoneOfMatcher struct {
+	Matchers []Matcher
+	actual   Param
+	sync.Mutex
+}
+
+// NoneOf matches params that none of matchers match, i.e. NoneOf(m) negates m.
+// Unlike AllOf and AnyOf, it has no alias named after the operator, since Not would collide with Gomega's Not,
+// which is commonly used in the same test files.
+func NoneOf(matchers ...Matcher) *NoneOfMatcher {
+	verify.Argument(len(matchers) > 0, "Must provide at least one matcher")
+	return &NoneOfMatcher{Matchers: matchers}
+}
+
+func (matcher *NoneOfMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	for _, m := range matcher.Matchers {
+		if m.Matches(param) {
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *NoneOfMatcher) FailureMessage() string {
+	if len(matcher.Matchers) == 1 {
+		return fmt.Sprintf("Expected: not %v; but got: %v", matcher.Matchers[0], matcher.actual)
+	}
+	return fmt.Sprintf("Expected: none of %v; but got: %v", formatMatchers(matcher.Matchers), matcher.actual)
+}
+
+func (matcher *NoneOfMatcher) useEquality(equality *equality) {
+	useEquality(matcher.Matchers, equality)
+}
+
+func (matcher *NoneOfMatcher) String() string {
+	if len(matcher.Matchers) == 1 {
+		return fmt.Sprintf("Not(%v)", matcher.Matchers[0])
+	}
+	return fmt.Sprintf("NoneOf(%v)", formatMatchers(matcher.Matchers))
+}
+
+type AllOfMatcher struct {
+	Matchers []Matcher
+	failed   Matcher
+	sync.Mutex
+}
+
+// AllOf matches params that all of matchers match.
+func AllOf(matchers ...Matcher) *AllOfMatcher {
+	verify.Argument(len(matchers) > 0, "Must provide at least one matcher")
+	return &AllOfMatcher{Matchers: matchers}
+}
+
+// And is an alias of AllOf.
+func And(matchers ...Matcher) *AllOfMatcher {
+	return AllOf(matchers...)
+}
+
+func (matcher *AllOfMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.failed = nil
+	for _, m := range matcher.Matchers {
+		if !m.Matches(param) {
+			matcher.failed = m
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *AllOfMatcher) FailureMessage() string {
+	if matcher.failed == nil {
+		return fmt.Sprintf("Expected: %v", matcher)
+	}
+	return matcher.failed.FailureMessage()
+}
+
+func (matcher *AllOfMatcher) useEquality(equality *equality) {
+	useEquality(matcher.Matchers, equality)
+}
+
+func (matcher *AllOfMatcher) String() string {
+	return fmt.Sprintf("AllOf(%v)", formatMatchers(matcher.Matchers))
+}
+
+type AnyOfMatcher struct {
+	Matchers []Matcher
+	actual   Param
+	sync.Mutex
+}
+
+// AnyOf matches params that at least one of matchers matches.
+func AnyOf(matchers ...Matcher) *AnyOfMatcher {
+	verify.Argument(len(matchers) > 0, "Must provide at least one matcher")
+	return &AnyOfMatcher{Matchers: matchers}
+}
+
+// Or is an alias of AnyOf.
+func Or(matchers ...Matcher) *AnyOfMatcher {
+	return AnyOf(matchers...)
+}
+
+func (matcher *AnyOfMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	for _, m := range matcher.Matchers {
+		if m.Matches(param) {
+			return true
+		}
+	}
+	return false
+}
+
+func (matcher *AnyOfMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: any of %v; but got: %v", formatMatchers(matcher.Matchers), matcher.actual)
+}
+
+func (matcher *AnyOfMatcher) useEquality(equality *equality) {
+	useEquality(matcher.Matchers, equality)
+}
+
+func (matcher *AnyOfMatcher) String() string {
+	return fmt.Sprintf("AnyOf(%v)", formatMatchers(matcher.Matchers))
+}
+
+type GomegaMatcherAdapter struct {
+	Matcher types.GomegaMatcher
+	actual  Param
+	err     error
+	sync.Mutex
+}
+
+// Gomega adapts a Gomega matcher, so it can be used as argument matcher.
+func Gomega(matcher types.GomegaMatcher) *GomegaMatcherAdapter {
+	verify.Argument(matcher != nil, "Must provide a non-nil matcher")
+	return &GomegaMatcherAdapter{Matcher: matcher}
+}
+
+func (matcher *GomegaMatcherAdapter) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	success, err := matcher.Matcher.Match(param)
+	matcher.err = err
+	return err == nil && success
+}
+
+func (matcher *GomegaMatcherAdapter) FailureMessage() string {
+	if matcher.err != nil {
+		return matcher.err.Error()
+	}
+	return matcher.Matcher.FailureMessage(matcher.actual)
+}
+
+// String describes the Gomega matcher by its type and fields, e.g. HaveLen{Count:3}.
+func (matcher *GomegaMatcherAdapter) String() string {
+	name := reflect.TypeOf(matcher.Matcher).String()
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "Matcher")
+	return fmt.Sprintf("%v%+v", name, reflect.Indirect(reflect.ValueOf(matcher.Matcher)).Interface())
+}
+
+// PredicateMatcher matches params that satisfy Predicate. Since Go cannot compare funcs, two PredicateMatchers never
+// compare equal, not even with the same predicate. Therefore, a later When with a PredicateMatcher does not replace
+// an earlier stubbing with the same predicate. The later stubbing takes precedence, but the earlier one remains, and
+// VerifyAllStubbingsUsed and WithUnusedStubbingsCheck report it if it is never used. Stub such invocations only once,
+// e.g. with chained ThenReturn calls for consecutive answers.
+type PredicateMatcher struct {
+	Predicate interface{}
+	actual    Param
+	sync.Mutex
+}
+
+// NewPredicateMatcher creates a matcher for params that satisfy predicate, which must be a func(T) bool.
+func NewPredicateMatcher(predicate interface{}) *PredicateMatcher {
+	predicateType := reflect.TypeOf(predicate)
+	verify.Argument(predicateType != nil && predicateType.Kind() == reflect.Func && !reflect.ValueOf(predicate).IsNil() &&
+		predicateType.NumIn() == 1 && !predicateType.IsVariadic() &&
+		predicateType.NumOut() == 1 && predicateType.Out(0).Kind() == reflect.Bool,
+		"Must provide a predicate of the form func(T) bool, but got %T", predicate)
+	return &PredicateMatcher{Predicate: predicate}
+}
+
+func (matcher *PredicateMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	paramType := reflect.TypeOf(matcher.Predicate).In(0)
+	var in reflect.Value
+	switch {
+	case param == nil:
+		switch paramType.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			in = reflect.Zero(paramType)
+		default:
+			return false
+		}
+	case reflect.TypeOf(param).AssignableTo(paramType):
+		in = reflect.ValueOf(param)
+	default:
+		return false
+	}
+	return reflect.ValueOf(matcher.Predicate).Call([]reflect.Value{in})[0].Bool()
+}
+
+func (matcher *PredicateMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: value satisfying %T; but got: %v", matcher.Predicate, matcher.actual)
+}
+
+func (matcher *PredicateMatcher) String() string {
+	return fmt.Sprintf("That(%T)", matcher.Predicate)
+}
+
+// ArgThat registers matcher for the next invocation of a mock and returns nil, so it can be used
+// directly as argument of type interface{}. For other types, use the typed ...That and ...ThatMatches variants.
+// Stubbings with predicates cannot be replaced by a later When, see PredicateMatcher.
+//
+// matcher can be a Gomega matcher or a predicate of the form func(T) bool:
+//
+//	When(object.Decode(ArgThat(gomega.BeAssignableToTypeOf(&Config{})))).ThenReturn(nil)
+//	When(object.Decode(ArgThat(func(c *Config) bool { return c.Name != "" }))).ThenReturn(nil)
+func ArgThat(matcher interface{}) interface{} {
+	RegisterMatcher(argThatMatcher("ArgThat", matcher))
+	return nil
+}
+
+func argThatMatcher(factoryName string, matcher interface{}) Matcher {
+	if gomegaMatcher, isGomegaMatcher := matcher.(types.GomegaMatcher); isGomegaMatcher {
+		return Gomega(gomegaMatcher)
+	}
+	verify.Argument(reflect.TypeOf(matcher) != nil && reflect.TypeOf(matcher).Kind() == reflect.Func,
+		"%v() requires a Gomega matcher or a predicate of the form func(T) bool, but got %T.", factoryName, matcher)
+	return NewPredicateMatcher(matcher)
+}
@@ -2,6 +2,8 @@ package pegomock
 
 import (
 	"reflect"
+
+	"github.com/onsi/gomega/types"
 )
 
 func EqBool(value bool) bool {
@@ -19,6 +21,26 @@ func AnyBoolSlice() []bool {
 	return nil
 }
 
+func BoolThat(predicate func(bool) bool) bool {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return false
+}
+
+func BoolThatMatches(matcher types.GomegaMatcher) bool {
+	RegisterMatcher(Gomega(matcher))
+	return false
+}
+
+func NotEqBool(value bool) bool {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return false
+}
+
+func BoolMatching(matcher Matcher) bool {
+	RegisterMatcher(matcher)
+	return false
+}
+
 func EqInt(value int) int {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -34,6 +56,41 @@ func AnyIntSlice() []int {
 	return nil
 }
 
+func IntThat(predicate func(int) bool) int {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func IntThatMatches(matcher types.GomegaMatcher) int {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqInt(value int) int {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func IntMatching(matcher Matcher) int {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func IntGreaterThan(value int) int {
+	RegisterMatcher(&NumericMatcher{Name: "IntGreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func IntLessThan(value int) int {
+	RegisterMatcher(&NumericMatcher{Name: "IntLessThan", Values: []Param{value}})
+	return 0
+}
+
+func IntBetween(min, max int) int {
+	RegisterMatcher(&NumericMatcher{Name: "IntBetween", Values: []Param{min, max}})
+	return 0
+}
+
 func EqInt8(value int8) int8 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -49,6 +106,41 @@ func AnyInt8Slice() []int8 {
 	return nil
 }
 
+func Int8That(predicate func(int8) bool) int8 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Int8ThatMatches(matcher types.GomegaMatcher) int8 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqInt8(value int8) int8 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Int8Matching(matcher Matcher) int8 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Int8GreaterThan(value int8) int8 {
+	RegisterMatcher(&NumericMatcher{Name: "Int8GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Int8LessThan(value int8) int8 {
+	RegisterMatcher(&NumericMatcher{Name: "Int8LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Int8Between(min, max int8) int8 {
+	RegisterMatcher(&NumericMatcher{Name: "Int8Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqInt16(value int16) int16 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -64,6 +156,41 @@ func AnyInt16Slice() []int16 {
 	return nil
 }
 
+func Int16That(predicate func(int16) bool) int16 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Int16ThatMatches(matcher types.GomegaMatcher) int16 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqInt16(value int16) int16 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Int16Matching(matcher Matcher) int16 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Int16GreaterThan(value int16) int16 {
+	RegisterMatcher(&NumericMatcher{Name: "Int16GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Int16LessThan(value int16) int16 {
+	RegisterMatcher(&NumericMatcher{Name: "Int16LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Int16Between(min, max int16) int16 {
+	RegisterMatcher(&NumericMatcher{Name: "Int16Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqInt32(value int32) int32 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -79,6 +206,41 @@ func AnyInt32Slice() []int32 {
 	return nil
 }
 
+func Int32That(predicate func(int32) bool) int32 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Int32ThatMatches(matcher types.GomegaMatcher) int32 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqInt32(value int32) int32 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Int32Matching(matcher Matcher) int32 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Int32GreaterThan(value int32) int32 {
+	RegisterMatcher(&NumericMatcher{Name: "Int32GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Int32LessThan(value int32) int32 {
+	RegisterMatcher(&NumericMatcher{Name: "Int32LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Int32Between(min, max int32) int32 {
+	RegisterMatcher(&NumericMatcher{Name: "Int32Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqInt64(value int64) int64 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -94,6 +256,41 @@ func AnyInt64Slice() []int64 {
 	return nil
 }
 
+func Int64That(predicate func(int64) bool) int64 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Int64ThatMatches(matcher types.GomegaMatcher) int64 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqInt64(value int64) int64 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Int64Matching(matcher Matcher) int64 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Int64GreaterThan(value int64) int64 {
+	RegisterMatcher(&NumericMatcher{Name: "Int64GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Int64LessThan(value int64) int64 {
+	RegisterMatcher(&NumericMatcher{Name: "Int64LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Int64Between(min, max int64) int64 {
+	RegisterMatcher(&NumericMatcher{Name: "Int64Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUint(value uint) uint {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -109,6 +306,41 @@ func AnyUintSlice() []uint {
 	return nil
 }
 
+func UintThat(predicate func(uint) bool) uint {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func UintThatMatches(matcher types.GomegaMatcher) uint {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUint(value uint) uint {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func UintMatching(matcher Matcher) uint {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func UintGreaterThan(value uint) uint {
+	RegisterMatcher(&NumericMatcher{Name: "UintGreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func UintLessThan(value uint) uint {
+	RegisterMatcher(&NumericMatcher{Name: "UintLessThan", Values: []Param{value}})
+	return 0
+}
+
+func UintBetween(min, max uint) uint {
+	RegisterMatcher(&NumericMatcher{Name: "UintBetween", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUint8(value uint8) uint8 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -124,6 +356,41 @@ func AnyUint8Slice() []uint8 {
 	return nil
 }
 
+func Uint8That(predicate func(uint8) bool) uint8 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Uint8ThatMatches(matcher types.GomegaMatcher) uint8 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUint8(value uint8) uint8 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Uint8Matching(matcher Matcher) uint8 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Uint8GreaterThan(value uint8) uint8 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint8GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint8LessThan(value uint8) uint8 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint8LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint8Between(min, max uint8) uint8 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint8Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUint16(value uint16) uint16 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -139,6 +406,41 @@ func AnyUint16Slice() []uint16 {
 	return nil
 }
 
+func Uint16That(predicate func(uint16) bool) uint16 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Uint16ThatMatches(matcher types.GomegaMatcher) uint16 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUint16(value uint16) uint16 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Uint16Matching(matcher Matcher) uint16 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Uint16GreaterThan(value uint16) uint16 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint16GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint16LessThan(value uint16) uint16 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint16LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint16Between(min, max uint16) uint16 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint16Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUint32(value uint32) uint32 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -154,6 +456,41 @@ func AnyUint32Slice() []uint32 {
 	return nil
 }
 
+func Uint32That(predicate func(uint32) bool) uint32 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Uint32ThatMatches(matcher types.GomegaMatcher) uint32 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUint32(value uint32) uint32 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Uint32Matching(matcher Matcher) uint32 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Uint32GreaterThan(value uint32) uint32 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint32GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint32LessThan(value uint32) uint32 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint32LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint32Between(min, max uint32) uint32 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint32Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUint64(value uint64) uint64 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -169,6 +506,41 @@ func AnyUint64Slice() []uint64 {
 	return nil
 }
 
+func Uint64That(predicate func(uint64) bool) uint64 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Uint64ThatMatches(matcher types.GomegaMatcher) uint64 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUint64(value uint64) uint64 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Uint64Matching(matcher Matcher) uint64 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Uint64GreaterThan(value uint64) uint64 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint64GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint64LessThan(value uint64) uint64 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint64LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Uint64Between(min, max uint64) uint64 {
+	RegisterMatcher(&NumericMatcher{Name: "Uint64Between", Values: []Param{min, max}})
+	return 0
+}
+
 func EqUintptr(value uintptr) uintptr {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -184,6 +556,41 @@ func AnyUintptrSlice() []uintptr {
 	return nil
 }
 
+func UintptrThat(predicate func(uintptr) bool) uintptr {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func UintptrThatMatches(matcher types.GomegaMatcher) uintptr {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqUintptr(value uintptr) uintptr {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func UintptrMatching(matcher Matcher) uintptr {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func UintptrGreaterThan(value uintptr) uintptr {
+	RegisterMatcher(&NumericMatcher{Name: "UintptrGreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func UintptrLessThan(value uintptr) uintptr {
+	RegisterMatcher(&NumericMatcher{Name: "UintptrLessThan", Values: []Param{value}})
+	return 0
+}
+
+func UintptrBetween(min, max uintptr) uintptr {
+	RegisterMatcher(&NumericMatcher{Name: "UintptrBetween", Values: []Param{min, max}})
+	return 0
+}
+
 func EqFloat32(value float32) float32 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -199,6 +606,46 @@ func AnyFloat32Slice() []float32 {
 	return nil
 }
 
+func Float32That(predicate func(float32) bool) float32 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Float32ThatMatches(matcher types.GomegaMatcher) float32 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqFloat32(value float32) float32 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Float32Matching(matcher Matcher) float32 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Float32GreaterThan(value float32) float32 {
+	RegisterMatcher(&NumericMatcher{Name: "Float32GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Float32LessThan(value float32) float32 {
+	RegisterMatcher(&NumericMatcher{Name: "Float32LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Float32Between(min, max float32) float32 {
+	RegisterMatcher(&NumericMatcher{Name: "Float32Between", Values: []Param{min, max}})
+	return 0
+}
+
+func Float32CloseTo(value, epsilon float32) float32 {
+	RegisterMatcher(&NumericMatcher{Name: "Float32CloseTo", Values: []Param{value, epsilon}})
+	return 0
+}
+
 func EqFloat64(value float64) float64 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -214,6 +661,46 @@ func AnyFloat64Slice() []float64 {
 	return nil
 }
 
+func Float64That(predicate func(float64) bool) float64 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Float64ThatMatches(matcher types.GomegaMatcher) float64 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqFloat64(value float64) float64 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Float64Matching(matcher Matcher) float64 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
+func Float64GreaterThan(value float64) float64 {
+	RegisterMatcher(&NumericMatcher{Name: "Float64GreaterThan", Values: []Param{value}})
+	return 0
+}
+
+func Float64LessThan(value float64) float64 {
+	RegisterMatcher(&NumericMatcher{Name: "Float64LessThan", Values: []Param{value}})
+	return 0
+}
+
+func Float64Between(min, max float64) float64 {
+	RegisterMatcher(&NumericMatcher{Name: "Float64Between", Values: []Param{min, max}})
+	return 0
+}
+
+func Float64CloseTo(value, epsilon float64) float64 {
+	RegisterMatcher(&NumericMatcher{Name: "Float64CloseTo", Values: []Param{value, epsilon}})
+	return 0
+}
+
 func EqComplex64(value complex64) complex64 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -229,6 +716,26 @@ func AnyComplex64Slice() []complex64 {
 	return nil
 }
 
+func Complex64That(predicate func(complex64) bool) complex64 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Complex64ThatMatches(matcher types.GomegaMatcher) complex64 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqComplex64(value complex64) complex64 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Complex64Matching(matcher Matcher) complex64 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
 func EqComplex128(value complex128) complex128 {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return 0
@@ -244,6 +751,26 @@ func AnyComplex128Slice() []complex128 {
 	return nil
 }
 
+func Complex128That(predicate func(complex128) bool) complex128 {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return 0
+}
+
+func Complex128ThatMatches(matcher types.GomegaMatcher) complex128 {
+	RegisterMatcher(Gomega(matcher))
+	return 0
+}
+
+func NotEqComplex128(value complex128) complex128 {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return 0
+}
+
+func Complex128Matching(matcher Matcher) complex128 {
+	RegisterMatcher(matcher)
+	return 0
+}
+
 func EqString(value string) string {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return ""
@@ -259,6 +786,26 @@ func AnyStringSlice() []string {
 	return nil
 }
 
+func StringThat(predicate func(string) bool) string {
+	RegisterMatcher(NewPredicateMatcher(predicate))
+	return ""
+}
+
+func StringThatMatches(matcher types.GomegaMatcher) string {
+	RegisterMatcher(Gomega(matcher))
+	return ""
+}
+
+func NotEqString(value string) string {
+	RegisterMatcher(NoneOf(&EqMatcher{Value: value}))
+	return ""
+}
+
+func StringMatching(matcher Matcher) string {
+	RegisterMatcher(matcher)
+	return ""
+}
+
 func EqInterface(value interface{}) interface{} {
 	RegisterMatcher(&EqMatcher{Value: value})
 	return nil
@@ -76,10 +76,11 @@ func (g *generator) generateCode(source string, pkg *model.Package, structName,
 	g.p(")")
 
 	for _, iface := range pkg.Interfaces {
-		if structName == "" {
-			structName = "Mock" + iface.Name
+		mockTypeName := structName
+		if mockTypeName == "" {
+			mockTypeName = "Mock" + iface.Name
 		}
-		g.generateMockFor(iface, structName, selfPackage)
+		g.generateMockFor(iface, mockTypeName, selfPackage)
 	}
 }
 
@@ -143,7 +144,10 @@ func sanitize(s string) string {
 }
 
 func (g *generator) generateMockFor(iface *model.Interface, mockTypeName, selfPackage string) {
-	g.generateMockType(mockTypeName)
+	g.generateMockType(mockTypeName, !hasMethod(iface, "GenericMock"))
+	if !hasMethod(iface, "Reset") {
+		g.generateMockResetMethod(mockTypeName)
+	}
 	for _, method := range iface.Methods {
 		g.generateMockMethod(mockTypeName, method, selfPackage)
 		g.emptyLine()
@@ -161,28 +165,71 @@ func (g *generator) generateMockFor(iface *model.Interface, mockTypeName, selfPa
 		g.generateOngoingVerificationGetCapturedArguments(ongoingVerificationTypeName, argNames, argTypes)
 		g.generateOngoingVerificationGetAllCapturedArguments(ongoingVerificationTypeName, argTypes, method.Variadic != nil)
 	}
+	if !hasMethod(iface, "Stub") {
+		g.generateStubberType(mockTypeName)
+		for _, method := range iface.Methods {
+			ongoingStubbingTypeName := fmt.Sprintf("%v_%v_OngoingStubbing", mockTypeName, method.Name)
+			g.generateStubberMethod(mockTypeName, method, selfPackage, ongoingStubbingTypeName)
+			g.generateOngoingStubbingType(method, selfPackage, ongoingStubbingTypeName)
+		}
+	}
 }
 
-func (g *generator) generateMockType(mockTypeName string) {
+// generateMockType generates the mock type. Unless ownsGenericMock, the mock does not keep its GenericMock itself,
+// but relies on pegomock looking it up, like for hand-written mocks.
+func (g *generator) generateMockType(mockTypeName string, ownsGenericMock bool) {
 	g.
 		emptyLine().
 		p("type %v struct {", mockTypeName).
-		p("	fail func(message string, callerSkip ...int)").
+		p("	fail        func(message string, callerSkip ...int)")
+	if ownsGenericMock {
+		g.p("	genericMock *pegomock.GenericMock")
+	}
+	g.
 		p("}").
 		emptyLine().
 		p("func New%v(options ...pegomock.Option) *%v {", mockTypeName, mockTypeName).
-		p("	mock := &%v{}", mockTypeName).
+		p("	mock := &%v{}", mockTypeName)
+	if ownsGenericMock {
+		g.p("	mock.genericMock = pegomock.NewGenericMock(mock)")
+	}
+	g.
 		p("	for _, option := range options {").
 		p("		option.Apply(mock)").
 		p("	}").
 		p("	return mock").
 		p("}").
 		emptyLine().
+		p("func init() {").
+		p("	pegomock.RegisterMockFactory(reflect.TypeOf((*%v)(nil)), func(options ...pegomock.Option) pegomock.Mock { return New%v(options...) })", mockTypeName, mockTypeName).
+		p("}").
+		emptyLine().
 		p("func (mock *%v) SetFailHandler(fh pegomock.FailHandler) { mock.fail = fh }", mockTypeName).
-		p("func (mock *%v) FailHandler() pegomock.FailHandler      { return mock.fail }", mockTypeName).
+		p("func (mock *%v) FailHandler() pegomock.FailHandler      { return mock.fail }", mockTypeName)
+	if ownsGenericMock {
+		g.p("func (mock *%v) GenericMock() *pegomock.GenericMock     { return mock.genericMock }", mockTypeName)
+	}
+	g.emptyLine()
+}
+
+func (g *generator) generateMockResetMethod(mockTypeName string) {
+	g.
+		p("func (mock *%v) Reset() {", mockTypeName).
+		p("	pegomock.Reset(mock)").
+		p("}").
 		emptyLine()
 }
 
+// hasMethod reports whether iface has a method with the given name, which generated helper methods must not shadow.
+func hasMethod(iface *model.Interface, name string) bool {
+	for _, method := range iface.Methods {
+		if method.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // If non-empty, pkgOverride is the package in which unqualified types reside.
 func (g *generator) generateMockMethod(mockType string, method *model.Method, pkgOverride string) *generator {
 	args, argNames, _, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
@@ -191,16 +238,15 @@ func (g *generator) generateMockMethod(mockType string, method *model.Method, pk
 		p("	panic(\"mock must not be nil. Use myMock := New%v().\")", mockType).
 		p("}")
 	g.GenerateParamsDeclaration(argNames, method.Variadic != nil)
-	reflectReturnTypes := make([]string, len(returnTypes))
-	for i, returnType := range returnTypes {
-		reflectReturnTypes[i] = fmt.Sprintf("reflect.TypeOf((*%v)(nil)).Elem()", returnType.String(g.packageMap, pkgOverride))
-	}
+	reflectReturnTypes := g.reflectTypesFor(returnTypes, pkgOverride)
 	resultAssignment := ""
 	if len(method.Out) > 0 {
 		resultAssignment = "result :="
 	}
-	g.p("%v pegomock.GetGenericMockFrom(mock).Invoke(\"%v\", params, []reflect.Type{%v})",
+	g.p("%v pegomock.GetGenericMockFrom(mock).InvokeWithCallThrough(\"%v\", params, []reflect.Type{%v}, func(_delegate interface{}) pegomock.ReturnValues {",
 		resultAssignment, method.Name, strings.Join(reflectReturnTypes, ", "))
+	g.generateCallThrough(method, args, argNames, returnTypes, pkgOverride)
+	g.p("})")
 	if len(method.Out) > 0 {
 		// TODO: translate LastInvocation into a Matcher so it can be used as key for Stubbings
 		for i, returnType := range returnTypes {
@@ -231,6 +277,48 @@ func (g *generator) generateMockMethod(mockType string, method *model.Method, pk
 	return g
 }
 
+func (g *generator) reflectTypesFor(types []model.Type, pkgOverride string) []string {
+	reflectTypes := make([]string, len(types))
+	for i, t := range types {
+		reflectTypes[i] = fmt.Sprintf("reflect.TypeOf((*%v)(nil)).Elem()", t.String(g.packageMap, pkgOverride))
+	}
+	return reflectTypes
+}
+
+// generateCallThrough generates the body of a func that calls the method on a delegate, which only needs to implement this very method.
+// If it doesn't, the func panics naming the method, rather than with a plain type assertion error.
+func (g *generator) generateCallThrough(method *model.Method, args []string, argNames []string, returnTypes []model.Type, pkgOverride string) {
+	callArgs := append([]string{}, argNames...)
+	if method.Variadic != nil {
+		callArgs[len(callArgs)-1] += "..."
+	}
+	returnTypeStrings := stringSliceFrom(returnTypes, g.packageMap, pkgOverride)
+	signature := fmt.Sprintf("%v(%v)", method.Name, join(args))
+	switch len(returnTypeStrings) {
+	case 0:
+	case 1:
+		signature += " " + returnTypeStrings[0]
+	default:
+		signature += " (" + join(returnTypeStrings) + ")"
+	}
+	g.p("_typedDelegate, _ok := _delegate.(interface{ %v })", signature).
+		p("if !_ok {").
+		p("	panic(%q)", fmt.Sprintf("Calling the real method requires the delegate to implement %v.", signature)).
+		p("}")
+	call := fmt.Sprintf("_typedDelegate.%v(%v)", method.Name, join(callArgs))
+	if len(returnTypes) == 0 {
+		g.p(call).
+			p("return nil")
+		return
+	}
+	returnValues := make([]string, len(returnTypes))
+	for i := range returnTypes {
+		returnValues[i] = fmt.Sprintf("ret%v", i)
+	}
+	g.p("%v := %v", strings.Join(returnValues, ", "), call).
+		p("return pegomock.ReturnValues{%v}", strings.Join(returnValues, ", "))
+}
+
 func (g *generator) generateVerifierType(interfaceName string) *generator {
 	return g.
 		p("type Verifier%v struct {", interfaceName).
@@ -285,6 +373,163 @@ func (g *generator) generateVerifierMethod(interfaceName string, method *model.M
 		p("}")
 }
 
+func (g *generator) generateStubberType(mockTypeName string) *generator {
+	return g.
+		p("func (mock *%v) Stub() *Stubber%v {", mockTypeName, mockTypeName).
+		p("	return &Stubber%v{mock: mock}", mockTypeName).
+		p("}").
+		emptyLine().
+		p("type Stubber%v struct {", mockTypeName).
+		p("	mock *%v", mockTypeName).
+		p("}").
+		emptyLine()
+}
+
+func (g *generator) generateStubberMethod(mockTypeName string, method *model.Method, pkgOverride string, ongoingStubbingTypeName string) *generator {
+	args, argNames, _, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
+	return g.
+		p("func (stubber *Stubber%v) %v(%v) *%v {", mockTypeName, method.Name, join(args), ongoingStubbingTypeName).
+		GenerateParamsDeclaration(argNames, method.Variadic != nil).
+		p("ongoingStubbing := pegomock.GetGenericMockFrom(stubber.mock).When(\"%v\", params, []reflect.Type{%v})",
+			method.Name, strings.Join(g.reflectTypesFor(returnTypes, pkgOverride), ", ")).
+		p("return &%v{ongoingStubbing: ongoingStubbing}", ongoingStubbingTypeName).
+		p("}").
+		emptyLine()
+}
+
+func (g *generator) generateOngoingStubbingType(method *model.Method, pkgOverride string, ongoingStubbingTypeName string) *generator {
+	_, _, _, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
+	returnValues := make([]string, len(returnTypes))
+	returnParams := make([]string, len(returnTypes))
+	for i, returnType := range returnTypes {
+		returnValues[i] = fmt.Sprintf("ret%v", i)
+		returnParams[i] = fmt.Sprintf("ret%v %v", i, returnType.String(g.packageMap, pkgOverride))
+	}
+	return g.
+		p("type %v struct {", ongoingStubbingTypeName).
+		p("	ongoingStubbing *pegomock.OngoingStubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenReturn(%v) *%v {", ongoingStubbingTypeName, join(returnParams), ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenReturn(%v)", join(returnValues)).
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenReturnTimes(n int, %v) *%v {", ongoingStubbingTypeName, join(returnParams), ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenReturnTimes(n, %v)", join(returnValues)).
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenReturnOnce(%v) *%v {", ongoingStubbingTypeName, join(returnParams), ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenReturnOnce(%v)", join(returnValues)).
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenPanic(v interface{}) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenPanic(v)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) Then(callback func([]pegomock.Param) pegomock.ReturnValues) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.Then(callback)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenCallRealMethod() *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenCallRealMethod()").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenDelay(d time.Duration) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenDelay(d)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenBlockUntil(ch <-chan struct{}) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenBlockUntil(ch)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenSetArg(index int, value interface{}) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenSetArg(index, value)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenInvokeArg(index int, args ...interface{}) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenInvokeArg(index, args...)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenInvokeArgAsync(index int, args ...interface{}) *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenInvokeArgAsync(index, args...)").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		p("func (stubbing *%v) ThenBlockUntilContextDone() *%v {", ongoingStubbingTypeName, ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.ThenBlockUntilContextDone()").
+		p("	return stubbing").
+		p("}").
+		emptyLine().
+		generateOngoingStubbingThenDo(method, pkgOverride, ongoingStubbingTypeName)
+}
+
+// generateOngoingStubbingThenDo generates ThenDo, which takes a callback with the method's own signature
+// and wraps it into an untyped callback.
+func (g *generator) generateOngoingStubbingThenDo(method *model.Method, pkgOverride string, ongoingStubbingTypeName string) *generator {
+	_, _, argTypes, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
+	callbackParamTypes := append([]string{}, argTypes...)
+	callArgs := make([]string, len(argTypes))
+	for i := range argTypes {
+		callArgs[i] = fmt.Sprintf("arg%v", i)
+	}
+	if method.Variadic != nil {
+		callbackParamTypes[len(argTypes)-1] = "..." + method.Variadic.Type.String(g.packageMap, pkgOverride)
+		callArgs[len(argTypes)-1] += "..."
+	}
+	g.
+		p("func (stubbing *%v) ThenDo(callback func(%v) (%v)) *%v {",
+			ongoingStubbingTypeName, join(callbackParamTypes), join(stringSliceFrom(returnTypes, g.packageMap, pkgOverride)), ongoingStubbingTypeName).
+		p("	stubbing.ongoingStubbing.Then(func(params []pegomock.Param) pegomock.ReturnValues {")
+	for i, in := range method.In {
+		g.
+			p("var arg%v %v", i, argTypes[i]).
+			p("if params[%v] != nil {", i).
+			p("	arg%v = params[%v].(%v)", i, i, in.Type.String(g.packageMap, pkgOverride)).
+			p("}")
+	}
+	if method.Variadic != nil {
+		variadicType := method.Variadic.Type.String(g.packageMap, pkgOverride)
+		g.
+			p("var arg%v %v", len(method.In), argTypes[len(method.In)]).
+			p("for _, param := range params[%v:] {", len(method.In)).
+			p("	var variadicArg %v", variadicType).
+			p("	if param != nil {").
+			p("		variadicArg = param.(%v)", variadicType).
+			p("	}").
+			p("	arg%v = append(arg%v, variadicArg)", len(method.In), len(method.In)).
+			p("}")
+	}
+	call := fmt.Sprintf("callback(%v)", join(callArgs))
+	if len(returnTypes) == 0 {
+		g.
+			p(call).
+			p("return nil")
+	} else {
+		returnValues := make([]string, len(returnTypes))
+		for i := range returnTypes {
+			returnValues[i] = fmt.Sprintf("ret%v", i)
+		}
+		g.
+			p("%v := %v", join(returnValues), call).
+			p("return pegomock.ReturnValues{%v}", join(returnValues))
+	}
+	return g.
+		p("	})").
+		p("	return stubbing").
+		p("}").
+		emptyLine()
+}
+
 func (g *generator) GenerateParamsDeclaration(argNames []string, isVariadic bool) *generator {
 	if isVariadic {
 		return g.
@@ -408,11 +653,14 @@ func addTypesFromMethodParamsTo(typesSet map[string]string, params []*model.Para
 		switch typedType := param.Type.(type) {
 		case *model.NamedType, *model.PointerType, *model.ArrayType, *model.MapType, *model.ChanType:
 			if _, exists := typesSet[underscoreNameFor(typedType, packageMap)]; !exists {
-				typesSet[underscoreNameFor(typedType, packageMap)] = generateMatcherSourceCode(typedType, packageMap)
+				typesSet[underscoreNameFor(typedType, packageMap)] = generateMatcherSourceCode(typedType, packageMap) +
+					generateCollectionMatcherSourceCode(typedType, packageMap) +
+					generateStructMatcherSourceCode(typedType, packageMap)
 			}
 		case *model.FuncType:
-			// matcher generation for funcs not supported yet
-			// TODO implement
+			if _, exists := typesSet[underscoreNameFor(typedType, packageMap)]; !exists {
+				typesSet[underscoreNameFor(typedType, packageMap)] = generateFuncMatcherSourceCode(typedType, packageMap)
+			}
 		case model.PredeclaredType:
 			// skip. These come as part of pegomock.
 		default:
@@ -427,6 +675,7 @@ package matchers
 
 import (
 	"reflect"
+	gomegatypes "github.com/onsi/gomega/types"
 	"github.com/petergtz/pegomock"
 	%v
 )
@@ -442,6 +691,18 @@ func Eq%v(value %v) %v {
 	var nullValue %v
 	return nullValue
 }
+
+func %vThat(predicate func(%v) bool) %v {
+	pegomock.RegisterMatcher(pegomock.NewPredicateMatcher(predicate))
+	var nullValue %v
+	return nullValue
+}
+
+func %vThatMatches(matcher gomegatypes.GomegaMatcher) %v {
+	pegomock.RegisterMatcher(pegomock.Gomega(matcher))
+	var nullValue %v
+	return nullValue
+}
 `,
 		optionalPackageOf(t, packageMap),
 		camelcaseNameFor(t, packageMap),
@@ -453,6 +714,106 @@ func Eq%v(value %v) %v {
 		t.String(packageMap, ""),
 		t.String(packageMap, ""),
 		t.String(packageMap, ""),
+
+		camelcaseNameFor(t, packageMap),
+		t.String(packageMap, ""),
+		t.String(packageMap, ""),
+		t.String(packageMap, ""),
+
+		camelcaseNameFor(t, packageMap),
+		t.String(packageMap, ""),
+		t.String(packageMap, ""),
+	)
+}
+
+// generateCollectionMatcherSourceCode generates typed variants of the collection matchers for slices, arrays and maps.
+func generateCollectionMatcherSourceCode(t model.Type, packageMap map[string]string) string {
+	name, typeString := camelcaseNameFor(t, packageMap), t.String(packageMap, "")
+	switch typedType := t.(type) {
+	case *model.ArrayType:
+		elemType := typedType.Type.String(packageMap, "")
+		return fmt.Sprintf(`
+func %[1]vContaining(elem %[3]v) %[2]v {
+	pegomock.SliceContaining(elem)
+	var nullValue %[2]v
+	return nullValue
+}
+
+func %[1]vContainingInAnyOrder(elems ...%[3]v) %[2]v {
+	params := make([]pegomock.Param, len(elems))
+	for i, elem := range elems {
+		params[i] = elem
+	}
+	pegomock.SliceContainingInAnyOrder(params...)
+	var nullValue %[2]v
+	return nullValue
+}
+
+func %[1]vOfLen(n int) %[2]v {
+	pegomock.SliceOfLen(n)
+	var nullValue %[2]v
+	return nullValue
+}
+`, name, typeString, elemType)
+	case *model.MapType:
+		return fmt.Sprintf(`
+func %[1]vHasKey(key %[3]v) %[2]v {
+	pegomock.MapHasKey(key)
+	var nullValue %[2]v
+	return nullValue
+}
+
+func %[1]vHasEntry(key %[3]v, value %[4]v) %[2]v {
+	pegomock.MapHasEntry(key, value)
+	var nullValue %[2]v
+	return nullValue
+}
+`, name, typeString, typedType.Key.String(packageMap, ""), typedType.Value.String(packageMap, ""))
+	default:
+		return ""
+	}
+}
+
+// generateStructMatcherSourceCode generates a typed variant of StructWithFields for struct types and pointers to them.
+func generateStructMatcherSourceCode(t model.Type, packageMap map[string]string) string {
+	namedType, isNamedType := t.(*model.NamedType)
+	if pointerType, isPointerType := t.(*model.PointerType); isPointerType {
+		namedType, isNamedType = pointerType.Type.(*model.NamedType)
+	}
+	if !isNamedType || !namedType.Struct {
+		return ""
+	}
+	return fmt.Sprintf(`
+func %[1]vWithFields(fields map[string]interface{}) %[2]v {
+	pegomock.RegisterMatcher(pegomock.StructWithFields(fields))
+	var nullValue %[2]v
+	return nullValue
+}
+`, camelcaseNameFor(t, packageMap), t.String(packageMap, ""))
+}
+
+// generateFuncMatcherSourceCode generates only an Any matcher, because funcs cannot be compared for equality.
+func generateFuncMatcherSourceCode(t *model.FuncType, packageMap map[string]string) string {
+	return fmt.Sprintf(`// Code generated by pegomock. DO NOT EDIT.
+package matchers
+
+import (
+	"reflect"
+	"github.com/petergtz/pegomock"
+	%v
+)
+
+func Any%v() %v {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(%v))(nil)).Elem()))
+	var nullValue %v
+	return nullValue
+}
+`,
+		optionalPackageOf(t, packageMap),
+		camelcaseNameFor(t, packageMap),
+		t.String(packageMap, ""),
+		t.String(packageMap, ""),
+		t.String(packageMap, ""),
 	)
 }
 
@@ -470,8 +831,15 @@ func optionalPackageOf(t model.Type, packageMap map[string]string) string {
 		return optionalPackageOf(typedType.Key, packageMap) + "\n" + optionalPackageOf(typedType.Value, packageMap)
 	case *model.ChanType:
 		return optionalPackageOf(typedType.Type, packageMap)
-		// TODO:
-	// case *model.FuncType:
+	case *model.FuncType:
+		packages := make(map[string]bool)
+		for _, param := range funcParamsOf(typedType) {
+			for _, pkg := range strings.Split(optionalPackageOf(param.Type, packageMap), "\n") {
+				packages[pkg] = true
+			}
+		}
+		delete(packages, "")
+		return strings.Join(util.SortedKeys(packages), "\n")
 	default:
 		panic(fmt.Sprintf("TODO implement optionalPackageOf for: %v\nis type of %T\n", typedType, typedType))
 	}
@@ -508,13 +876,39 @@ func spaceSeparatedNameFor(t model.Type, packageMap map[string]string) string {
 		default:
 			return "chan of " + spaceSeparatedNameFor(typedType.Type, packageMap)
 		}
-	// TODO:
-	// case *model.FuncType:
+	case *model.FuncType:
+		name := "func"
+		if len(typedType.In) > 0 || typedType.Variadic != nil {
+			params := make([]string, len(typedType.In))
+			for i, param := range typedType.In {
+				params[i] = spaceSeparatedNameFor(param.Type, packageMap)
+			}
+			if typedType.Variadic != nil {
+				params = append(params, "variadic "+spaceSeparatedNameFor(typedType.Variadic.Type, packageMap))
+			}
+			name += " of " + strings.Join(params, " and ")
+		}
+		if len(typedType.Out) > 0 {
+			results := make([]string, len(typedType.Out))
+			for i, result := range typedType.Out {
+				results[i] = spaceSeparatedNameFor(result.Type, packageMap)
+			}
+			name += " returning " + strings.Join(results, " and ")
+		}
+		return name
 	default:
 		return fmt.Sprintf("TODO implement matcher for: %v\nis type of %T\n", typedType, typedType)
 	}
 }
 
+func funcParamsOf(funcType *model.FuncType) []*model.Parameter {
+	params := append(append([]*model.Parameter{}, funcType.In...), funcType.Out...)
+	if funcType.Variadic != nil {
+		params = append(params, funcType.Variadic)
+	}
+	return params
+}
+
 func camelcaseNameFor(t model.Type, packageMap map[string]string) string {
 	return strings.Replace(strings.Title(strings.Replace(spaceSeparatedNameFor(t, packageMap), "_", " ", -1)), " ", "", -1)
 }
@@ -1,7 +1,10 @@
 package mockgen_test
 
 import (
+	"strings"
+
 	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/model"
 	"github.com/petergtz/pegomock/modelgen/loader"
 
 	. "github.com/onsi/ginkgo"
@@ -16,28 +19,42 @@ var _ = Describe("Mockgen", func() {
 			_, matcherSourceCodes := mockgen.GenerateOutput(ast, "irrelevant", "MockDisplay", "test_package", "")
 
 			Expect(matcherSourceCodes).To(SatisfyAll(
-				HaveLen(9),
+				HaveLen(10),
 				HaveKeyWithValue("http_request", SatisfyAll(
 					ContainSubstring("http \"net/http\""),
 					ContainSubstring("func AnyHttpRequest() http.Request"),
+					ContainSubstring("func HttpRequestWithFields(fields map[string]interface{}) http.Request"),
 				)),
 				HaveKeyWithValue("ptr_to_http_request", SatisfyAll(
 					ContainSubstring("http \"net/http\""),
 					ContainSubstring("func AnyPtrToHttpRequest() *http.Request"),
+					ContainSubstring("func PtrToHttpRequestWithFields(fields map[string]interface{}) *http.Request"),
 				)),
-				HaveKeyWithValue("slice_of_string",
+				HaveKeyWithValue("slice_of_string", SatisfyAll(
 					ContainSubstring("func AnySliceOfString() []string"),
-				),
+					ContainSubstring("func SliceOfStringThat(predicate func([]string) bool) []string"),
+					ContainSubstring("func SliceOfStringContaining(elem string) []string"),
+					ContainSubstring("func SliceOfStringContainingInAnyOrder(elems ...string) []string"),
+					ContainSubstring("func SliceOfStringOfLen(n int) []string"),
+					ContainSubstring("func SliceOfStringThatMatches(matcher gomegatypes.GomegaMatcher) []string"),
+				)),
 				HaveKeyWithValue("map_of_string_to_http_request", SatisfyAll(
 					ContainSubstring("http \"net/http\""),
 					ContainSubstring("func AnyMapOfStringToHttpRequest() map[string]http.Request"),
+					ContainSubstring("func MapOfStringToHttpRequestHasKey(key string) map[string]http.Request"),
+					ContainSubstring("func MapOfStringToHttpRequestHasEntry(key string, value http.Request) map[string]http.Request"),
 				)),
 				HaveKeyWithValue("io_readcloser", SatisfyAll(
 					ContainSubstring("func AnyIoReadCloser() io.ReadCloser"),
+					Not(ContainSubstring("WithFields")),
 				)),
 				HaveKeyWithValue("map_of_string_to_interface", SatisfyAll(
 					ContainSubstring("func AnyMapOfStringToInterface() map[string]interface{}"),
 				)),
+				HaveKeyWithValue("func", SatisfyAll(
+					ContainSubstring("func AnyFunc() func()"),
+					Not(ContainSubstring("func EqFunc(")),
+				)),
 				HaveKeyWithValue("time_time", SatisfyAll(
 					ContainSubstring("time \"time\""),
 					ContainSubstring("func AnyTimeTime() time.Time"),
@@ -51,4 +68,74 @@ var _ = Describe("Mockgen", func() {
 			))
 		})
 	})
+
+	Context("typed stubbers", func() {
+		It("generates a Stub method returning a stubber with typed ongoing stubbings", func() {
+			ast, e := loader.GenerateModel("github.com/petergtz/pegomock/test_interface", "Display")
+			Expect(e).NotTo(HaveOccurred())
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "MockDisplay", "test_package", "")
+
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("func (mock *MockDisplay) Stub() *StubberMockDisplay {"),
+				ContainSubstring("func (stubber *StubberMockDisplay) MultipleParamsAndReturnValue(s string, i int) *MockDisplay_MultipleParamsAndReturnValue_OngoingStubbing {"),
+				ContainSubstring("func (stubbing *MockDisplay_MultipleParamsAndReturnValue_OngoingStubbing) ThenReturn(ret0 string) *MockDisplay_MultipleParamsAndReturnValue_OngoingStubbing {"),
+			))
+		})
+
+		It("does not generate a Stub method when the interface already has one", func() {
+			ast := &model.Package{Name: "test_package", Interfaces: []*model.Interface{{
+				Name:    "Stubbable",
+				Methods: []*model.Method{{Name: "Stub"}},
+			}}}
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "MockStubbable", "test_package", "")
+
+			Expect(string(output)).NotTo(ContainSubstring("StubberMockStubbable"))
+		})
+	})
+
+	Context("multiple interfaces", func() {
+		It("names each mock after its interface", func() {
+			ast := &model.Package{Name: "test_package", Interfaces: []*model.Interface{{Name: "First"}, {Name: "Second"}}}
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "", "test_package", "")
+
+			Expect(string(output)).To(ContainSubstring("type MockFirst struct {"))
+			Expect(string(output)).To(ContainSubstring("type MockSecond struct {"))
+		})
+	})
+
+	Context("GenericMock method", func() {
+		It("does not generate a GenericMock method when the interface already has one", func() {
+			ast := &model.Package{Name: "test_package", Interfaces: []*model.Interface{{
+				Name:    "Owner",
+				Methods: []*model.Method{{Name: "GenericMock"}},
+			}}}
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "MockOwner", "test_package", "")
+
+			Expect(string(output)).To(ContainSubstring("func (mock *MockOwner) GenericMock() {"))
+			Expect(string(output)).NotTo(ContainSubstring("GenericMock() *pegomock.GenericMock"))
+			Expect(string(output)).NotTo(ContainSubstring("pegomock.NewGenericMock(mock)"))
+		})
+	})
+
+	Context("Reset method", func() {
+		It("generates a Reset method", func() {
+			ast, e := loader.GenerateModel("github.com/petergtz/pegomock/test_interface", "Display")
+			Expect(e).NotTo(HaveOccurred())
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "MockDisplay", "test_package", "")
+
+			Expect(string(output)).To(ContainSubstring("func (mock *MockDisplay) Reset() {"))
+		})
+
+		It("does not generate a Reset method when the interface already has one", func() {
+			ast := &model.Package{Name: "test_package", Interfaces: []*model.Interface{{
+				Name:    "Resettable",
+				Methods: []*model.Method{{Name: "Reset"}},
+			}}}
+			output, _ := mockgen.GenerateOutput(ast, "irrelevant", "MockResettable", "test_package", "")
+
+			Expect(string(output)).To(ContainSubstring("func (mock *MockResettable) Reset() {"))
+			Expect(strings.Count(string(output), ") Reset() {")).To(Equal(1))
+			Expect(string(output)).NotTo(ContainSubstring("pegomock.Reset(mock)"))
+		})
+	})
 })
@@ -222,6 +222,7 @@ func (mt *MapType) addImports(im map[string]bool) {
 type NamedType struct {
 	Package string // may be empty
 	Type    string // TODO: should this be typed Type?
+	Struct  bool   // whether the underlying type is a struct
 }
 
 func (nt *NamedType) String(pm map[string]string, pkgOverride string) string {
@@ -20,10 +20,12 @@ import (
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
 	"log"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -48,6 +50,8 @@ func ParseFile(source string) (*model.Package, error) {
 		fileSet:       fs,
 		imports:       make(map[string]string),
 		auxInterfaces: make(map[string]map[string]*ast.InterfaceType),
+		source:        source,
+		structTypes:   make(map[string]map[string]bool),
 	}
 
 	// Handle -imports.
@@ -89,6 +93,9 @@ type fileParser struct {
 
 	auxFiles      []*ast.File
 	auxInterfaces map[string]map[string]*ast.InterfaceType // package (or "") => name => interface
+
+	source      string
+	structTypes map[string]map[string]bool // package (or "") => name => whether it is a struct
 }
 
 func (p *fileParser) errorf(pos token.Pos, format string, args ...interface{}) error {
@@ -127,6 +134,46 @@ func (p *fileParser) addAuxInterfacesFromFile(pkg string, file *ast.File) {
 	}
 }
 
+// isStructType reports whether name is a struct type in package pkg, which is an import path or "" for this file's package.
+func (p *fileParser) isStructType(pkg, name string) bool {
+	structTypes, parsed := p.structTypes[pkg]
+	if !parsed {
+		structTypes = p.parseStructTypes(pkg)
+		p.structTypes[pkg] = structTypes
+	}
+	return structTypes[name]
+}
+
+// parseStructTypes finds the struct types of package pkg only by parsing its source files.
+// This way, just like the rest of source mode, it does not require building the package.
+func (p *fileParser) parseStructTypes(pkg string) map[string]bool {
+	var sources []string
+	var buildPkg *build.Package
+	var err error
+	if pkg == "" {
+		sources = append(sources, p.source)
+		buildPkg, err = build.ImportDir(filepath.Dir(p.source), 0)
+	} else {
+		buildPkg, err = build.Import(pkg, filepath.Dir(p.source), 0)
+	}
+	if err == nil {
+		for _, goFile := range buildPkg.GoFiles {
+			sources = append(sources, filepath.Join(buildPkg.Dir, goFile))
+		}
+	}
+	structTypes := make(map[string]bool)
+	for _, source := range sources {
+		file, err := parser.ParseFile(token.NewFileSet(), source, nil, 0)
+		if err != nil {
+			continue
+		}
+		for name := range structTypesOf(file) {
+			structTypes[name] = true
+		}
+	}
+	return structTypes
+}
+
 func (p *fileParser) parseFile(file *ast.File) (*model.Package, error) {
 	allImports := importsOfFile(file)
 	// Don't stomp imports provided by -imports. Those should take precedence.
@@ -320,7 +367,7 @@ func (p *fileParser) parseType(pkg string, typ ast.Expr) (model.Type, error) {
 	case *ast.Ident:
 		if v.IsExported() {
 			// assume type in this package
-			return &model.NamedType{Package: pkg, Type: v.Name}, nil
+			return &model.NamedType{Package: pkg, Type: v.Name, Struct: p.isStructType(pkg, v.Name)}, nil
 		} else {
 			// assume predeclared type
 			return model.PredeclaredType(v.Name), nil
@@ -346,7 +393,7 @@ func (p *fileParser) parseType(pkg string, typ ast.Expr) (model.Type, error) {
 		if !ok {
 			return nil, p.errorf(v.Pos(), "unknown package %q", pkgName)
 		}
-		return &model.NamedType{Package: pkg, Type: v.Sel.String()}, nil
+		return &model.NamedType{Package: pkg, Type: v.Sel.String(), Struct: p.isStructType(pkg, v.Sel.String())}, nil
 	case *ast.StarExpr:
 		t, err := p.parseType(pkg, v.X)
 		if err != nil {
@@ -443,6 +490,25 @@ func iterInterfaces(file *ast.File) <-chan namedInterface {
 	return ch
 }
 
+// structTypesOf returns the names of all struct types in file.
+func structTypesOf(file *ast.File) map[string]bool {
+	structTypes := make(map[string]bool)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok {
+				if _, isStruct := ts.Type.(*ast.StructType); isStruct {
+					structTypes[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+	return structTypes
+}
+
 // isVariadic returns whether the function is variadic.
 func isVariadic(f *ast.FuncType) bool {
 	nargs := len(f.Params.List)
@@ -97,6 +97,7 @@ func typeFromType(t reflect.Type) (model.Type, error) {
 		return &model.NamedType{
 			Package: imp,
 			Type:    t.Name(),
+			Struct:  t.Kind() == reflect.Struct,
 		}, nil
 	}
 
@@ -146,9 +146,11 @@ func (g *modelGenerator) modelTypeFrom(typesType types.Type) model.Type {
 		if typedTyp.Obj().Pkg() == nil {
 			return model.PredeclaredType(typedTyp.Obj().Name())
 		}
+		_, isStruct := typedTyp.Underlying().(*types.Struct)
 		return &model.NamedType{
 			Package: typedTyp.Obj().Pkg().Path(),
 			Type:    typedTyp.Obj().Name(),
+			Struct:  isStruct,
 		}
 	case *types.Interface:
 		return model.PredeclaredType(typedTyp.String())
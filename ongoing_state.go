@@ -0,0 +1,166 @@
+package pegomock
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// ongoingState is what builds up between registering argument matchers, invoking a mock
+// and finally calling When or a verification method. All of this happens on one goroutine,
+// so the state is kept per goroutine. This way, tests running in parallel don't interfere.
+type ongoingState struct {
+	lastInvocation         *invocation
+	argMatchers            Matchers
+	stubbingFuncInProgress bool
+	// argMatchersHint explains how surplus arg matchers have likely been registered.
+	argMatchersHint string
+}
+
+func (state *ongoingState) isEmpty() bool {
+	return state.lastInvocation == nil && len(state.argMatchers) == 0 && state.argMatchersHint == "" && !state.stubbingFuncInProgress
+}
+
+const minPruneThreshold = 64
+
+var (
+	ongoingStatesMutex sync.Mutex
+	ongoingStates      = make(map[uint64]*ongoingState)
+	pruneThreshold     = minPruneThreshold
+)
+
+// withOngoingState calls f with the ongoing state of the current goroutine.
+// f must not call back into withOngoingState.
+func withOngoingState(f func(state *ongoingState)) {
+	goroutineID := currentGoroutineID()
+	ongoingStatesMutex.Lock()
+	defer ongoingStatesMutex.Unlock()
+	state, exists := ongoingStates[goroutineID]
+	if !exists {
+		state = &ongoingState{}
+	}
+	f(state)
+	if state.isEmpty() {
+		delete(ongoingStates, goroutineID)
+		return
+	}
+	ongoingStates[goroutineID] = state
+	if !exists && len(ongoingStates) > pruneThreshold {
+		pruneOngoingStatesOfFinishedGoroutines()
+	}
+}
+
+// takeArgMatchers takes the registered arg matchers of the current goroutine, together with the hint
+// to report if there are more of them than params.
+func takeArgMatchers() (argMatchers Matchers, hint string) {
+	withOngoingState(func(state *ongoingState) {
+		argMatchers, hint = state.argMatchers, state.argMatchersHint
+		state.argMatchers, state.argMatchersHint = nil, ""
+	})
+	return
+}
+
+// hintAtSurplusArgMatchers records hint for the case that arg matchers are registered already,
+// since they might have been registered by mistake, e.g. while building the fields of StructWithFields.
+func hintAtSurplusArgMatchers(hint string) {
+	withOngoingState(func(state *ongoingState) {
+		if len(state.argMatchers) != 0 {
+			state.argMatchersHint = hint
+		}
+	})
+}
+
+// setLastInvocation makes invocation the last invocation of the current goroutine and returns the one it replaces,
+// together with whether a stubbing is in progress.
+func setLastInvocation(invocation *invocation) (previous *invocation, argMatchersRegistered, stubbingFuncInProgress bool) {
+	withOngoingState(func(state *ongoingState) {
+		previous = state.lastInvocation
+		state.lastInvocation = invocation
+		argMatchersRegistered = len(state.argMatchers) != 0
+		stubbingFuncInProgress = state.stubbingFuncInProgress
+	})
+	return
+}
+
+func takeLastInvocation() (lastInvocation *invocation) {
+	withOngoingState(func(state *ongoingState) {
+		lastInvocation = state.lastInvocation
+		state.lastInvocation = nil
+	})
+	return
+}
+
+func discardOngoingState() {
+	withOngoingState(func(state *ongoingState) { *state = ongoingState{} })
+}
+
+// discardOngoingStatesOf drops the last invocations on genericMock of all goroutines.
+// Invocations from goroutines other than the test's are never consumed by a When, and hence would be kept forever.
+func discardOngoingStatesOf(genericMock *GenericMock) {
+	ongoingStatesMutex.Lock()
+	defer ongoingStatesMutex.Unlock()
+	for goroutineID, state := range ongoingStates {
+		if state.lastInvocation != nil && state.lastInvocation.genericMock == genericMock {
+			state.lastInvocation = nil
+		}
+		if state.isEmpty() {
+			delete(ongoingStates, goroutineID)
+		}
+	}
+}
+
+// pruneOngoingStatesOfFinishedGoroutines must be called with ongoingStatesMutex held.
+func pruneOngoingStatesOfFinishedGoroutines() {
+	liveGoroutineIDs := make(map[uint64]bool)
+	for _, goroutineID := range allGoroutineIDs() {
+		liveGoroutineIDs[goroutineID] = true
+	}
+	for goroutineID := range ongoingStates {
+		if !liveGoroutineIDs[goroutineID] {
+			delete(ongoingStates, goroutineID)
+		}
+	}
+	pruneThreshold = 2 * len(ongoingStates)
+	if pruneThreshold < minPruneThreshold {
+		pruneThreshold = minPruneThreshold
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+func currentGoroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	return parseGoroutineID(buf)
+}
+
+func allGoroutineIDs() []uint64 {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var goroutineIDs []uint64
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		goroutineIDs = append(goroutineIDs, parseGoroutineID(stack))
+	}
+	return goroutineIDs
+}
+
+// parseGoroutineID parses the ID from a stack trace, which starts like "goroutine 123 [running]:".
+func parseGoroutineID(stack []byte) uint64 {
+	stack = bytes.TrimPrefix(stack, goroutinePrefix)
+	if i := bytes.IndexByte(stack, ' '); i >= 0 {
+		stack = stack[:i]
+	}
+	goroutineID, err := strconv.ParseUint(string(stack), 10, 64)
+	if err != nil {
+		panic("Could not determine goroutine ID: " + err.Error())
+	}
+	return goroutineID
+}
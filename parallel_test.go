@@ -0,0 +1,79 @@
+package pegomock_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+)
+
+const numGoroutines = 50
+
+func TestStubbingAndVerifyingInParallelTests(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprintf("test %v", i), func(t *testing.T) {
+			t.Parallel()
+			ctx := pegomock.NewContext(t)
+			display := NewMockDisplay(ctx)
+
+			for j := 0; j < 100; j++ {
+				pegomock.When(display.MultipleParamsAndReturnValue(pegomock.EqString(fmt.Sprint(i)), pegomock.AnyInt())).ThenReturn(fmt.Sprint(j))
+				if result := display.MultipleParamsAndReturnValue(fmt.Sprint(i), j); result != fmt.Sprint(j) {
+					t.Fatalf("Expected %v, but got %v", j, result)
+				}
+				display.VerifyWasCalledOnce().MultipleParamsAndReturnValue(pegomock.EqString(fmt.Sprint(i)), pegomock.EqInt(j))
+			}
+		})
+	}
+}
+
+func TestStubbingAndVerifyingFromManyGoroutines(t *testing.T) {
+	ctx := pegomock.NewContext(t)
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			display := NewMockDisplay(ctx)
+			pegomock.When(display.MultipleParamsAndReturnValue(pegomock.AnyString(), pegomock.EqInt(i))).ThenReturn(fmt.Sprint(i))
+			pegomock.When(func() { display.SomeValue() }).ThenReturn("some value")
+
+			if result := display.MultipleParamsAndReturnValue("x", i); result != fmt.Sprint(i) {
+				t.Errorf("Expected %v, but got %v", i, result)
+			}
+			if result := display.SomeValue(); result != "some value" {
+				t.Errorf("Expected \"some value\", but got %v", result)
+			}
+			display.VerifyWasCalledOnce().MultipleParamsAndReturnValue(pegomock.EqString("x"), pegomock.AnyInt())
+			display.VerifyWasCalledOnce().SomeValue()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestInvokingSharedMockFromManyGoroutines(t *testing.T) {
+	ctx := pegomock.NewContext(t)
+	display := NewMockDisplay(ctx)
+	pegomock.When(display.MultipleParamsAndReturnValue(pegomock.AnyString(), pegomock.AnyInt())).ThenReturn("one").ThenReturn("two")
+
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if result := display.MultipleParamsAndReturnValue("x", i); result != "one" && result != "two" {
+					t.Errorf("Unexpected result %v", result)
+				}
+			}
+			// Stubbing another method concurrently must not corrupt the shared stubbing.
+			pegomock.When(func() { display.Flash(pegomock.EqString(fmt.Sprint(i)), pegomock.AnyInt()) }).ThenReturn()
+		}(i)
+	}
+	wg.Wait()
+
+	display.VerifyWasCalled(pegomock.Times(numGoroutines*10)).MultipleParamsAndReturnValue(pegomock.AnyString(), pegomock.AnyInt())
+	display.VerifyWasCalled(pegomock.Never()).Flash(pegomock.AnyString(), pegomock.AnyInt())
+}
@@ -0,0 +1,126 @@
+package pegomock
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// StringMatcher matches strings, or byte slices for the Bytes... factories, that satisfy the condition named by Name
+// on Value, e.g. StringContaining. It holds no funcs, so a later When with an equal StringMatcher replaces the stubbing.
+type StringMatcher struct {
+	Name   string
+	Value  string
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *StringMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	switch actual := param.(type) {
+	case string:
+		return matcher.match(actual)
+	case []byte:
+		return actual != nil && matcher.match(string(actual))
+	default:
+		return false
+	}
+}
+
+func (matcher *StringMatcher) match(actual string) bool {
+	switch matcher.Name {
+	case "StringContaining", "BytesContaining":
+		return strings.Contains(actual, matcher.Value)
+	case "StringHasPrefix", "BytesHasPrefix":
+		return strings.HasPrefix(actual, matcher.Value)
+	case "StringHasSuffix", "BytesHasSuffix":
+		return strings.HasSuffix(actual, matcher.Value)
+	case "StringMatchingRegex", "BytesMatchingRegex":
+		return compileRegex(matcher.Name, matcher.Value).MatchString(actual)
+	case "StringEqualFold", "BytesEqualFold":
+		return strings.EqualFold(actual, matcher.Value)
+	default:
+		panic(fmt.Sprintf("Unknown string matcher %v", matcher.Name))
+	}
+}
+
+func (matcher *StringMatcher) FailureMessage() string {
+	switch matcher.actual.(type) {
+	case string, []byte:
+		return fmt.Sprintf("Expected: %v; but got: %q", matcher, matcher.actual)
+	default:
+		return fmt.Sprintf("Expected: %v; but got: %v", matcher, matcher.actual)
+	}
+}
+
+func (matcher *StringMatcher) String() string {
+	return fmt.Sprintf("%v(%q)", matcher.Name, matcher.Value)
+}
+
+func registerStringMatcher(name string, value string) {
+	RegisterMatcher(&StringMatcher{Name: name, Value: value})
+}
+
+func compileRegex(factoryName string, pattern string) *regexp.Regexp {
+	regex, err := regexp.Compile(pattern)
+	verify.Argument(err == nil, "%v() got invalid regex %q: %v", factoryName, pattern, err)
+	return regex
+}
+
+func StringContaining(substr string) string {
+	registerStringMatcher("StringContaining", substr)
+	return ""
+}
+
+func StringHasPrefix(prefix string) string {
+	registerStringMatcher("StringHasPrefix", prefix)
+	return ""
+}
+
+func StringHasSuffix(suffix string) string {
+	registerStringMatcher("StringHasSuffix", suffix)
+	return ""
+}
+
+func StringMatchingRegex(pattern string) string {
+	compileRegex("StringMatchingRegex", pattern)
+	registerStringMatcher("StringMatchingRegex", pattern)
+	return ""
+}
+
+func StringEqualFold(value string) string {
+	registerStringMatcher("StringEqualFold", value)
+	return ""
+}
+
+func BytesContaining(subslice []byte) []byte {
+	registerStringMatcher("BytesContaining", string(subslice))
+	return nil
+}
+
+func BytesHasPrefix(prefix []byte) []byte {
+	registerStringMatcher("BytesHasPrefix", string(prefix))
+	return nil
+}
+
+func BytesHasSuffix(suffix []byte) []byte {
+	registerStringMatcher("BytesHasSuffix", string(suffix))
+	return nil
+}
+
+func BytesMatchingRegex(pattern string) []byte {
+	compileRegex("BytesMatchingRegex", pattern)
+	registerStringMatcher("BytesMatchingRegex", pattern)
+	return nil
+}
+
+func BytesEqualFold(value []byte) []byte {
+	registerStringMatcher("BytesEqualFold", string(value))
+	return nil
+}
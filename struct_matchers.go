@@ -0,0 +1,140 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// StructMatcher matches structs, or pointers to structs, by the fields in Fields only.
+type StructMatcher struct {
+	Fields     map[string]Param
+	mismatches []string
+	equality   *equality
+	sync.Mutex
+}
+
+// StructWithFields matches structs whose fields have the given values. Keys are field names or dotted paths
+// into nested structs, like "Address.City". Pointers along the path are dereferenced. Values can be Matchers
+// that do not register themselves, like those created by Eq, NotEq, That, AllOf, AnyOf and NoneOf,
+// e.g. StructWithFields(map[string]interface{}{"Name": "Tom", "ID": That(func(id int) bool { return id > 0 })}).
+func StructWithFields(fields map[string]interface{}) *StructMatcher {
+	verify.Argument(len(fields) > 0, "Must provide at least one field")
+	matcherFields := make(map[string]Param, len(fields))
+	var zeroValuedPaths []string
+	for path, value := range fields {
+		verify.Argument(path != "" && !strings.HasPrefix(path, ".") && !strings.HasSuffix(path, ".") && !strings.Contains(path, ".."),
+			"Invalid field path %q", path)
+		matcherFields[path] = value
+		if v := reflect.ValueOf(value); !v.IsValid() || v.IsZero() {
+			zeroValuedPaths = append(zeroValuedPaths, path)
+		}
+	}
+	// Matcher factories like EqString register their matcher and return a zero value. Their matchers cannot be told
+	// apart from those of preceding params yet, but surplus matchers will be reported with this hint.
+	if len(zeroValuedPaths) != 0 {
+		sort.Strings(zeroValuedPaths)
+		hintAtSurplusArgMatchers(fmt.Sprintf(
+			"StructWithFields() got zero values for fields %v. If they have been created by matcher factories like EqString(),\n"+
+				"use matchers that do not register themselves, like Eq(), NotEq() or That(), instead.",
+			strings.Join(zeroValuedPaths, ", ")))
+	}
+	return &StructMatcher{Fields: matcherFields}
+}
+
+func (matcher *StructMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.mismatches = nil
+	for _, path := range matcher.sortedPaths() {
+		actual, err := fieldByPath(reflect.ValueOf(param), path)
+		if err != nil {
+			matcher.mismatches = append(matcher.mismatches, fmt.Sprintf("%v: %v", path, err))
+			continue
+		}
+		if expectedMatcher, isMatcher := matcher.Fields[path].(Matcher); isMatcher {
+			if !expectedMatcher.Matches(actual) {
+				matcher.mismatches = append(matcher.mismatches, fmt.Sprintf("%v: %v", path, expectedMatcher.FailureMessage()))
+			}
+		} else if !matcher.equality.equal(matcher.Fields[path], actual) {
+			matcher.mismatches = append(matcher.mismatches,
+				fmt.Sprintf("%v: Expected: %#v; but got: %#v", path, matcher.Fields[path], actual))
+		}
+	}
+	return len(matcher.mismatches) == 0
+}
+
+func (matcher *StructMatcher) FailureMessage() string {
+	return "Mismatching fields:\n\t\t" + strings.Join(matcher.mismatches, "\n\t\t")
+}
+
+func (matcher *StructMatcher) String() string {
+	fields := make([]string, len(matcher.Fields))
+	for i, path := range matcher.sortedPaths() {
+		if expectedMatcher, isMatcher := matcher.Fields[path].(Matcher); isMatcher {
+			fields[i] = fmt.Sprintf("%v: %v", path, expectedMatcher)
+		} else {
+			fields[i] = fmt.Sprintf("%v: %#v", path, matcher.Fields[path])
+		}
+	}
+	return fmt.Sprintf("StructWithFields(%v)", strings.Join(fields, ", "))
+}
+
+func (matcher *StructMatcher) useEquality(equality *equality) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matcher.equality == nil {
+		matcher.equality = equality
+	}
+	for _, value := range matcher.Fields {
+		if user, isEqualityUser := value.(equalityUser); isEqualityUser {
+			user.useEquality(equality)
+		}
+	}
+}
+
+func (matcher *StructMatcher) sortedPaths() []string {
+	paths := make([]string, 0, len(matcher.Fields))
+	for path := range matcher.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// fieldByPath follows the dotted path through nested structs, dereferencing pointers and interfaces.
+func fieldByPath(value reflect.Value, path string) (Param, error) {
+	for _, name := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, fmt.Errorf("cannot access field %v of nil %v", name, value.Type())
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			if !value.IsValid() {
+				return nil, fmt.Errorf("cannot access field %v of nil", name)
+			}
+			return nil, fmt.Errorf("cannot access field %v of non-struct %v", name, value.Type())
+		}
+		field, exists := value.Type().FieldByName(name)
+		if !exists {
+			return nil, fmt.Errorf("%v has no field %v", value.Type(), name)
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("cannot access unexported field %v of %v", name, value.Type())
+		}
+		fieldValue, err := value.FieldByIndexErr(field.Index)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access field %v of %v through nil embedded pointer", name, value.Type())
+		}
+		value = fieldValue
+	}
+	return value.Interface(), nil
+}
@@ -0,0 +1,7 @@
+package test_interface
+
+// Broker is a sample interface with a callback-style API.
+type Broker interface {
+	Subscribe(topic string, handler func(message string, attempt int)) error
+	Publish(topic string, payload []byte) error
+}
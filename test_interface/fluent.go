@@ -0,0 +1,19 @@
+package test_interface
+
+import "context"
+
+// Storage is a sample interface with a fluent API.
+type Storage interface {
+	Bucket(name string) Bucket
+}
+
+type Bucket interface {
+	Object(name string) Object
+}
+
+type Object interface {
+	Read() ([]byte, error)
+	ReadWithContext(ctx context.Context) ([]byte, error)
+	Decode(v interface{}) error
+	Scan(dest ...interface{}) error
+}
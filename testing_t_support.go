@@ -22,6 +22,7 @@
 package pegomock
 
 import (
+	"reflect"
 	"regexp"
 	"runtime/debug"
 	"strings"
@@ -31,8 +32,15 @@ type testingT interface {
 	Errorf(format string, args ...interface{})
 }
 
+type helper interface {
+	Helper()
+}
+
 func BuildTestingTFailHandler(t testingT) FailHandler {
 	return func(message string, callerSkip ...int) {
+		if h, ok := t.(helper); ok {
+			h.Helper()
+		}
 		skip := 1
 		if len(callerSkip) > 0 {
 			skip = callerSkip[0]
@@ -58,6 +66,61 @@ func pruneStack(fullStackTrace string, skip int) string {
 	return strings.Join(prunedStack, "\n")
 }
 
+// WithT makes the mock report failures via t. If t is a testing.TB, the mock also registers
+// a cleanup function with it, which runs deferred verifications and the checks for unused stubbings
+// and finally resets the mock, when the test ends.
+//
+// GinkgoT() of Ginkgo v1 has a Cleanup method, but never runs the registered functions. Mocks bound to it
+// therefore reject DeferVerification and WithUnusedStubbingsCheck. Verify them at the end of the test instead.
 func WithT(t testingT) Option {
-	return WithFailHandler(BuildTestingTFailHandler(t))
+	if h, ok := t.(helper); ok {
+		h.Helper()
+	}
+	return OptionFunc(func(mock Mock) {
+		mock.SetFailHandler(BuildTestingTFailHandler(t))
+		if c, ok := t.(cleanupRegistrar); ok {
+			GetGenericMockFrom(mock).registerCleanup(c)
+		}
+	})
+}
+
+const cleanupIgnoredMessage = "%v requires a test that runs cleanup functions, but GinkgoT() of Ginkgo v1 ignores them. %v"
+
+// runsCleanups reports whether t runs the functions registered with Cleanup, which GinkgoT() of Ginkgo v1 doesn't.
+func runsCleanups(t cleanupRegistrar) bool {
+	tType := reflect.TypeOf(t)
+	if tType.Kind() == reflect.Ptr {
+		tType = tType.Elem()
+	}
+	return !strings.HasSuffix(tType.PkgPath(), "github.com/onsi/ginkgo/internal/testingtproxy")
 }
+
+// MockContext binds a group of mocks to a test. Every mock created with the context as option reports failures
+// via the test and is cleaned up when the test ends, just like with WithT. Since pegomock keeps ongoing stubbings
+// and registered argument matchers per goroutine, tests using their own MockContext can safely run in parallel.
+type MockContext struct {
+	t           testingT
+	failHandler FailHandler
+}
+
+// NewContext creates a MockContext for t. If t is a testing.TB, the context also discards any unfinished stubbing
+// of the test's goroutine when the test ends.
+func NewContext(t testingT) *MockContext {
+	if h, ok := t.(helper); ok {
+		h.Helper()
+	}
+	if c, ok := t.(cleanupRegistrar); ok {
+		c.Cleanup(discardOngoingState)
+	}
+	return &MockContext{t: t, failHandler: BuildTestingTFailHandler(t)}
+}
+
+func (context *MockContext) Apply(mock Mock) {
+	mock.SetFailHandler(context.failHandler)
+	if c, ok := context.t.(cleanupRegistrar); ok {
+		GetGenericMockFrom(mock).registerCleanup(c)
+	}
+}
+
+// FailHandler returns the FailHandler shared by all mocks of the context.
+func (context *MockContext) FailHandler() FailHandler { return context.failHandler }
@@ -1,5 +1,7 @@
 package pegomock
 
+import "github.com/petergtz/pegomock/internal/verify"
+
 type FailHandler func(message string, callerSkip ...int)
 
 type Mock interface {
@@ -19,3 +21,37 @@ func (f OptionFunc) Apply(mock Mock) { f(mock) }
 func WithFailHandler(fail FailHandler) Option {
 	return OptionFunc(func(mock Mock) { mock.SetFailHandler(fail) })
 }
+
+// Strictness determines how a mock reacts to invocations that have not been stubbed.
+type Strictness int
+
+const (
+	// Lenient mocks return zero values for unstubbed invocations. This is the default.
+	Lenient Strictness = iota
+	// Strict mocks call their FailHandler as soon as an unstubbed method is invoked. This includes the invocation
+	// within When(mock.Method("raw value")), so stub them with argument matchers, When(func() { ... }) or Stub() instead.
+	// To also report unused stubbings at the end of the test, use WithUnusedStubbingsCheck.
+	Strict
+)
+
+func WithStrictness(strictness Strictness) Option {
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).strictness = strictness })
+}
+
+// WithDelegate turns the mock into a spy: invocations that have not been stubbed are forwarded to delegate,
+// which is usually a real implementation of the mocked interface. Invocations are recorded for verification as usual.
+func WithDelegate(delegate interface{}) Option {
+	return OptionFunc(func(mock Mock) { GetGenericMockFrom(mock).delegate = delegate })
+}
+
+// WithUnusedStubbingsCheck makes the mock report stubbings that have never been used
+// through its FailHandler when the test ends.
+func WithUnusedStubbingsCheck(t cleanupRegistrar) Option {
+	verify.Argument(runsCleanups(t), cleanupIgnoredMessage,
+		"WithUnusedStubbingsCheck", "Please call VerifyAllStubbingsUsed at the end of the test instead.")
+	return OptionFunc(func(mock Mock) {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.checkUnusedStubbings = true
+		genericMock.registerCleanup(t)
+	})
+}